@@ -35,4 +35,17 @@ func main() {
 	fmt.Printf("Use: %s\n", jwk.Use)
 	fmt.Printf("RSA Public Key Modulus size: %d bits\n", pubKey.N.BitLen())
 	fmt.Printf("RSA Public Key Exponent: %d\n", pubKey.E)
+	fmt.Println()
+
+	// A JWKSCache honors the caching headers of the issuer, refreshes the keys in the
+	// background and refetches the document when an unknown kid shows up after a rotation.
+	cache := jwt.NewJWKSCache("https://www.googleapis.com/oauth2/v3/certs")
+	cache.Start()
+	defer cache.Stop()
+
+	if _, err := cache.FindKeyByKid(targetKid); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("Found key with Kid %s in cache\n", targetKid)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/loggdme/strivia/jwt"
@@ -14,8 +15,11 @@ type CustomClaims struct {
 }
 
 func main() {
-	privateKeyStr := "MC4CAQAwBQYDK2VwBCIEIBNHmOiDd/gS4XOKxzBA+yLQ+9i9eFH50y1CfNYW8u2e"
-	publicKeyStr := "MCowBQYDK2VwAyEAIcjUkocF8Vxw6BcY3c8nx1DjgXcCLlqwFfLkma+uJr4="
+	privateKey, publicKey, _ := jwt.GenerateEd25519KeyPair()
+
+	privateKeyPEM, _ := jwt.EncodeEd25519PrivateKeyPEM(&privateKey)
+	publicKeyPEM, _ := jwt.EncodeEd25519PublicKeyPEM(&publicKey)
+	fmt.Printf("Private Key:\n%s\nPublic Key:\n%s\n", privateKeyPEM, publicKeyPEM)
 
 	newToken := jwt.NewToken(&CustomClaims{
 		Email: "user@loggd.me",
@@ -30,15 +34,21 @@ func main() {
 		},
 	})
 
-	privateKey, _ := jwt.ParseEd25519PrivateKey(privateKeyStr)
-	publicKey, _ := jwt.ParseEd25519PublicKey(publicKeyStr)
-
 	signedToken, _ := newToken.SignedString(&privateKey)
 	fmt.Printf("Signed Token: %s\n\n", signedToken)
 
 	expectedClaims := jwt.ExpectedClaims{
 		Issuer:   "loggd.me",
 		Audience: []string{"loggd.me"},
+		Validators: []jwt.Validator{
+			jwt.RequireType("JWT"),
+			jwt.Check(func(claims CustomClaims) error {
+				if !strings.HasSuffix(claims.Email, "@loggd.me") {
+					return &jwt.ClaimError{Claim: "email", Err: jwt.ErrClaimInvalid}
+				}
+				return nil
+			}),
+		},
 	}
 
 	parsedToken, _ := jwt.VerifyToken[CustomClaims](signedToken, &publicKey, &expectedClaims)
@@ -0,0 +1,126 @@
+package jwt
+
+import (
+	"slices"
+	"strings"
+)
+
+// AccessTokenType is the `typ` header of JWT access tokens, see
+// https://datatracker.ietf.org/doc/html/rfc9068#section-2.1.
+const AccessTokenType = "at+jwt"
+
+// AccessTokenClaims are the claims of the JWT profile for OAuth 2.0 access tokens, see
+// https://datatracker.ietf.org/doc/html/rfc9068#section-2.2. Custom access token claims can
+// embed AccessTokenClaims instead of RegisteredClaims.
+type AccessTokenClaims struct {
+	// the `client_id` claim, the OAuth 2.0 client the token was issued to.
+	ClientID string `json:"client_id,omitempty"`
+
+	// the `scope` claim, a space separated list of the granted scopes.
+	Scope string `json:"scope,omitempty"`
+
+	// the `auth_time` claim, when the user last authenticated.
+	AuthTime *NumericDate `json:"auth_time,omitempty"`
+
+	// the `acr` claim, the authentication context class the authentication satisfied.
+	ACR string `json:"acr,omitempty"`
+
+	// the `amr` claim, the authentication methods used, e.g. `pwd` or `otp`.
+	AMR []string `json:"amr,omitempty"`
+
+	// the `cnf` claim, binding the token to a DPoP key, see [RequireDPoPBinding].
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	// the `act` claim, the party acting on behalf of the subject after a token exchange.
+	Actor *Actor `json:"act,omitempty"`
+
+	// the `may_act` claim, the party which is allowed to act on behalf of the subject.
+	MayAct *Actor `json:"may_act,omitempty"`
+
+	RegisteredClaims
+}
+
+// Actor is the `act` or `may_act` claim of a token, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-4.1. A token obtained through a chain
+// of delegations nests the prior actors, the outermost Actor is the current one.
+type Actor struct {
+	Subject string `json:"sub"`
+	Issuer  string `json:"iss,omitempty"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// Chain returns the current actor followed by all prior actors.
+func (a *Actor) Chain() []*Actor {
+	var chain []*Actor
+	for actor := a; actor != nil; actor = actor.Actor {
+		chain = append(chain, actor)
+	}
+
+	return chain
+}
+
+// Scopes returns the granted scopes of the `scope` claim.
+func (c AccessTokenClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the `scope` claim grants scope.
+func (c AccessTokenClaims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// NewAccessToken creates a new access token with the `typ` header set to `at+jwt`, so it
+// cannot be mistaken for an ID token or any other JWT signed with the same key.
+func NewAccessToken[T Claims](claims *T) *Token[T] {
+	token := NewToken(claims)
+	token.Header["typ"] = AccessTokenType
+
+	return token
+}
+
+// VerifyAccessToken verifies an access token like [VerifyToken], and additionally requires the
+// `at+jwt` type and the `client_id` and `jti` claims. Tokens of any other type, e.g. ID tokens
+// signed with the same key, are rejected.
+func VerifyAccessToken[T Claims](tokenString string, key *PublicKey, expected *ExpectedClaims) (*Token[T], error) {
+	return VerifyToken[T](tokenString, key, accessTokenExpectedClaims(expected))
+}
+
+// VerifyAccessTokenWithKeySet verifies an access token like [VerifyTokenWithKeySet] with the
+// additional requirements of [VerifyAccessToken].
+func VerifyAccessTokenWithKeySet[T Claims](tokenString string, ks *KeySet, expected *ExpectedClaims) (*Token[T], error) {
+	return VerifyTokenWithKeySet[T](tokenString, ks, accessTokenExpectedClaims(expected))
+}
+
+// VerifyAccessTokenWithJWKS verifies an access token like [VerifyTokenWithJWKS] with the
+// additional requirements of [VerifyAccessToken].
+func VerifyAccessTokenWithJWKS[T Claims](tokenString string, jwks JWKSource, algorithms []string, expected *ExpectedClaims) (*Token[T], error) {
+	return VerifyTokenWithJWKS[T](tokenString, jwks, algorithms, accessTokenExpectedClaims(expected))
+}
+
+// accessTokenExpectedClaims returns a copy of expected with the validators required by
+// https://datatracker.ietf.org/doc/html/rfc9068#section-4 in front of its own validators.
+// RFC 9068 does not list `nbf` among the required claims, so it is only checked if present.
+func accessTokenExpectedClaims(expected *ExpectedClaims) *ExpectedClaims {
+	var e ExpectedClaims
+	if expected != nil {
+		e = *expected
+	}
+
+	e.NotBeforeOptional = true
+	e.Validators = append([]Validator{RequireType(AccessTokenType), requireStringClaims("client_id", "jti")}, e.Validators...)
+
+	return &e
+}
+
+// requireStringClaims requires every claim with one of the given names to be a non-empty string.
+func requireStringClaims(names ...string) Validator {
+	return func(ctx *ValidationContext) error {
+		for _, name := range names {
+			if value, ok := ClaimValue[string](ctx, name); !ok || value == "" {
+				return &ClaimError{Claim: name, Err: ErrClaimIsRequired}
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func validTestAccessTokenClaims() *AccessTokenClaims {
+	return &AccessTokenClaims{
+		ClientID:         "client-1",
+		Scope:            "read write",
+		AMR:              []string{"pwd", "otp"},
+		RegisteredClaims: *validTestClaims(),
+	}
+}
+
+func TestAccessToken_IssueAndVerify(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := validTestAccessTokenClaims()
+	claims.ID = "token-1"
+
+	token := NewAccessToken(claims)
+	if token.Header["typ"] != "at+jwt" {
+		t.Fatalf("expected typ 'at+jwt', got %v", token.Header["typ"])
+	}
+
+	signed, _ := token.SignedString(&privateKey)
+
+	parsed, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected access token to verify, got %v", err)
+	}
+
+	if !parsed.Claims.HasScope("write") || parsed.Claims.HasScope("admin") {
+		t.Errorf("unexpected scopes %v", parsed.Claims.Scopes())
+	}
+	if parsed.Claims.ClientID != "client-1" || len(parsed.Claims.AMR) != 2 {
+		t.Errorf("unexpected claims %+v", parsed.Claims)
+	}
+}
+
+func TestVerifyAccessToken_RejectsOtherTypes(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := validTestAccessTokenClaims()
+	claims.ID = "token-1"
+
+	idToken, _ := NewToken(claims).SignedString(&privateKey)
+	_, err := VerifyAccessToken[AccessTokenClaims](idToken, &publicKey, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenInvalidType) {
+		t.Errorf("expected ErrTokenInvalidType for 'JWT' typ, got %v", err)
+	}
+
+	token := NewAccessToken(claims)
+	token.Header["typ"] = "application/at+jwt"
+	signed, _ := token.SignedString(&privateKey)
+	if _, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected 'application/at+jwt' to be accepted, got %v", err)
+	}
+}
+
+func TestVerifyAccessToken_RequiredClaims(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := validTestAccessTokenClaims()
+	claims.ID = "token-1"
+	claims.ClientID = ""
+
+	signed, _ := NewAccessToken(claims).SignedString(&privateKey)
+
+	_, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims())
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.HasClaim("client_id") {
+		t.Errorf("expected missing client_id to fail, got %v", err)
+	}
+
+	claims = validTestAccessTokenClaims()
+	signed, _ = NewAccessToken(claims).SignedString(&privateKey)
+
+	_, err = VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims())
+	if !errors.As(err, &validationErr) || !validationErr.HasClaim("jti") {
+		t.Errorf("expected missing jti to fail, got %v", err)
+	}
+
+	expected := validTestExpectedClaims()
+	expected.Validators = []Validator{RequireClaims("scope")}
+	VerifyAccessToken[AccessTokenClaims](signed, &publicKey, expected)
+	if len(expected.Validators) != 1 {
+		t.Error("expected the caller's ExpectedClaims not to be modified")
+	}
+}
+
+func TestAccessTokenClaims_Actor(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := validTestAccessTokenClaims()
+	claims.ID = "token-1"
+	claims.Actor = &Actor{Subject: "service-b", Actor: &Actor{Subject: "service-a", Issuer: "https://issuer.example.com"}}
+
+	signed, _ := NewAccessToken(claims).SignedString(&privateKey)
+
+	parsed, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected access token to verify, got %v", err)
+	}
+
+	chain := parsed.Claims.Actor.Chain()
+	if len(chain) != 2 || chain[0].Subject != "service-b" || chain[1].Subject != "service-a" || chain[1].Issuer == "" {
+		t.Errorf("unexpected actor chain %+v", chain)
+	}
+
+	var actor *Actor
+	if len(actor.Chain()) != 0 {
+		t.Error("expected empty chain for a token without actor")
+	}
+}
+
+func TestVerifyAccessToken_NotBeforeOptional(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := validTestAccessTokenClaims()
+	claims.ID = "token-1"
+	claims.NotBefore = nil
+
+	signed, _ := NewAccessToken(claims).SignedString(&privateKey)
+	if _, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected access token without nbf to verify, got %v", err)
+	}
+
+	claims.NotBefore = &NumericDate{Time: time.Now().Add(time.Hour)}
+	signed, _ = NewAccessToken(claims).SignedString(&privateKey)
+	if _, err := VerifyAccessToken[AccessTokenClaims](signed, &publicKey, validTestExpectedClaims()); !errors.Is(err, ErrTokenNotValidYet) {
+		t.Errorf("expected ErrTokenNotValidYet for future nbf, got %v", err)
+	}
+}
@@ -0,0 +1,474 @@
+package jwt
+
+import (
+	"container/heap"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	strivia_random "github.com/loggdme/strivia/random"
+)
+
+var (
+	ErrDPoPInvalidProof      = errors.New("jwt-dpop: proof is invalid")
+	ErrDPoPInvalidType       = errors.New("jwt-dpop: proof has no 'dpop+jwt' type")
+	ErrDPoPInvalidKey        = errors.New("jwt-dpop: proof has no valid public 'jwk' header")
+	ErrDPoPMethodMismatch    = errors.New("jwt-dpop: proof 'htm' does not match the request method")
+	ErrDPoPURIMismatch       = errors.New("jwt-dpop: proof 'htu' does not match the request URI")
+	ErrDPoPInvalidIssuedAt   = errors.New("jwt-dpop: proof 'iat' is outside of the acceptable window")
+	ErrDPoPInvalidNonce      = errors.New("jwt-dpop: proof has a missing or invalid 'nonce'")
+	ErrDPoPTokenHashMismatch = errors.New("jwt-dpop: proof 'ath' does not match the access token")
+	ErrDPoPReplayed          = errors.New("jwt-dpop: proof was already used")
+	ErrDPoPKeyMismatch       = errors.New("jwt-dpop: proof key does not match the 'cnf' claim of the access token")
+)
+
+// DPoPClaims are the claims of a DPoP proof JWT, see
+// https://datatracker.ietf.org/doc/html/rfc9449#section-4.2.
+type DPoPClaims struct {
+	ID              string       `json:"jti"`
+	Method          string       `json:"htm"`
+	URI             string       `json:"htu"`
+	IssuedAt        *NumericDate `json:"iat"`
+	AccessTokenHash string       `json:"ath,omitempty"`
+	Nonce           string       `json:"nonce,omitempty"`
+}
+
+// Confirmation is the `cnf` claim of an access token which is bound to a DPoP key, see
+// https://datatracker.ietf.org/doc/html/rfc9449#section-6.1. Access token claims carry it in
+// a field tagged `json:"cnf,omitempty"`.
+type Confirmation struct {
+	JWKThumbprint string `json:"jkt,omitempty"`
+}
+
+// DPoPKey is the key pair a client proves possession of with DPoP proofs. It should be
+// generated once per client instance and never leave it.
+type DPoPKey struct {
+	ed25519 *PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	jwk     *JWK
+}
+
+// GenerateDPoPKey generates a new DPoP key for the algorithm alg, which is either EdDSA
+// (Ed25519) or ES256 (ECDSA P-256).
+func GenerateDPoPKey(alg string) (*DPoPKey, error) {
+	switch alg {
+	case "EdDSA":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		key := PrivateKey(privateKey)
+		return NewEd25519DPoPKey(&key)
+	case SigningMethodES256.Name:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewECDSADPoPKey(privateKey)
+	}
+
+	return nil, ErrTokenInvalidAlgorithm
+}
+
+// NewEd25519DPoPKey creates a DPoP key from an existing Ed25519 private key.
+func NewEd25519DPoPKey(key *PrivateKey) (*DPoPKey, error) {
+	if key == nil || len(*key) != ed25519.PrivateKeySize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	publicKey := PublicKey(ed25519.PrivateKey(*key).Public().(ed25519.PublicKey))
+
+	return &DPoPKey{ed25519: key, jwk: &JWK{Kty: "OKP", Crv: "Ed25519", X: _EncodeSegment(publicKey)}}, nil
+}
+
+// NewECDSADPoPKey creates a DPoP key from an existing ECDSA P-256 private key.
+func NewECDSADPoPKey(key *ecdsa.PrivateKey) (*DPoPKey, error) {
+	if key == nil || key.Curve != elliptic.P256() {
+		return nil, ErrECDSAInvalidCurve
+	}
+
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	size := SigningMethodES256.KeySize
+	return &DPoPKey{ecdsa: key, jwk: &JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   _EncodeSegment(point[1 : 1+size]),
+		Y:   _EncodeSegment(point[1+size:]),
+	}}, nil
+}
+
+// Algorithm returns the JWS algorithm of the proofs signed with the key.
+func (k *DPoPKey) Algorithm() string {
+	if k.ecdsa != nil {
+		return SigningMethodES256.Name
+	}
+
+	return "EdDSA"
+}
+
+// JWK returns the public key as JWK, as it is embedded into every proof.
+func (k *DPoPKey) JWK() *JWK {
+	jwk := *k.jwk
+	return &jwk
+}
+
+// Thumbprint returns the JWK SHA-256 thumbprint of the public key, which the authorization
+// server puts into the `cnf.jkt` claim of access tokens bound to this key.
+func (k *DPoPKey) Thumbprint() string {
+	return k.jwk.thumbprint()
+}
+
+// Proof creates a DPoP proof for an HTTP request with the given method and URI. If accessToken
+// is not empty the proof is bound to it with the `ath` claim, which is required when calling
+// resource servers. The nonce is only set if the server provided one with `DPoP-Nonce`.
+func (k *DPoPKey) Proof(method string, uri string, accessToken string, nonce string) (string, error) {
+	htu, err := normalizeHTU(uri)
+	if err != nil {
+		return "", err
+	}
+
+	claims := DPoPClaims{
+		ID:       strivia_random.SecureRandomBase32String(32),
+		Method:   method,
+		URI:      htu,
+		IssuedAt: &NumericDate{Time: time.Now()},
+		Nonce:    nonce,
+	}
+	if accessToken != "" {
+		claims.AccessTokenHash = accessTokenHash(accessToken)
+	}
+
+	header, err := json.Marshal(map[string]any{"typ": "dpop+jwt", "alg": k.Algorithm(), "jwk": k.jwk})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingString := _EncodeSegment(header) + "." + _EncodeSegment(payload)
+
+	var sig []byte
+	if k.ecdsa != nil {
+		sig, err = SigningMethodES256.SignECDSA(signingString, k.ecdsa)
+	} else {
+		sig, err = SignEd25519(signingString, k.ed25519)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return signingString + "." + _EncodeSegment(sig), nil
+}
+
+// DPoPProof is a verified DPoP proof.
+type DPoPProof struct {
+	Header     map[string]any
+	Claims     *DPoPClaims
+	JWK        *JWK
+	Thumbprint string // Thumbprint is the JWK SHA-256 thumbprint of the key of the proof
+}
+
+// JTIStore remembers the `jti` of DPoP proofs to detect replays. Implementations backed by a
+// shared store are required if several instances verify proofs.
+type JTIStore interface {
+	// Use records jti until the given time and reports whether it was used for the first time.
+	Use(jti string, until time.Time) (bool, error)
+}
+
+// MemoryJTIStore is an in-memory JTIStore for a single instance. It is safe for concurrent use,
+// and its zero value is an empty store ready to use.
+type MemoryJTIStore struct {
+	mu     sync.Mutex
+	jtis   map[string]time.Time
+	expiry jtiHeap
+	now    func() time.Time
+}
+
+// NewMemoryJTIStore creates a new, empty MemoryJTIStore.
+func NewMemoryJTIStore() *MemoryJTIStore {
+	return &MemoryJTIStore{jtis: make(map[string]time.Time), now: time.Now}
+}
+
+// Use implements the JTIStore interface. Entries are kept ordered by their expiry, so only
+// the expired entries are visited when pruning on every call.
+func (s *MemoryJTIStore) Use(jti string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jtis == nil {
+		s.jtis = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	for len(s.expiry) > 0 && now.After(s.expiry[0].until) {
+		delete(s.jtis, heap.Pop(&s.expiry).(jtiEntry).jti)
+	}
+
+	if _, ok := s.jtis[jti]; ok {
+		return false, nil
+	}
+
+	s.jtis[jti] = until
+	heap.Push(&s.expiry, jtiEntry{jti: jti, until: until})
+
+	return true, nil
+}
+
+type jtiEntry struct {
+	jti   string
+	until time.Time
+}
+
+// jtiHeap is a min-heap of jtiEntry ordered by expiry, see [container/heap].
+type jtiHeap []jtiEntry
+
+func (h jtiHeap) Len() int           { return len(h) }
+func (h jtiHeap) Less(i, j int) bool { return h[i].until.Before(h[j].until) }
+func (h jtiHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *jtiHeap) Push(x any)        { *h = append(*h, x.(jtiEntry)) }
+
+func (h *jtiHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// The defaults of [NewDPoPVerifier], which also apply to a DPoPVerifier without Algorithms or
+// MaxAge.
+var defaultDPoPAlgorithms = []string{"EdDSA", "ES256"}
+
+const defaultDPoPMaxAge = 5 * time.Minute
+
+// DPoPVerifier verifies DPoP proofs as described in
+// https://datatracker.ietf.org/doc/html/rfc9449#section-4.3. A DPoPVerifier created as a struct
+// literal uses the defaults of [NewDPoPVerifier] for empty Algorithms and a zero MaxAge.
+type DPoPVerifier struct {
+	// Algorithms allowed for proofs, EdDSA and ES256 by default.
+	Algorithms []string
+	// MaxAge is how long a proof is accepted after its `iat`, and Leeway how far `iat` may be
+	// in the future.
+	MaxAge time.Duration
+	Leeway time.Duration
+	// JTIs detects replayed proofs. A nil store disables replay protection.
+	JTIs JTIStore
+	// ValidNonce, if set, requires proofs to carry a `nonce` claim for which it returns true.
+	ValidNonce func(nonce string) bool
+
+	now func() time.Time
+}
+
+// NewDPoPVerifier creates and returns a new DPoPVerifier which accepts proofs for five minutes
+// with one minute of clock skew and detects replays with jtis.
+func NewDPoPVerifier(jtis JTIStore) *DPoPVerifier {
+	return &DPoPVerifier{
+		Algorithms: slices.Clone(defaultDPoPAlgorithms),
+		MaxAge:     defaultDPoPMaxAge,
+		Leeway:     time.Minute,
+		JTIs:       jtis,
+		now:        time.Now,
+	}
+}
+
+// Verify verifies the proof from the `DPoP` header of a request with the given method and URI.
+// If the request carries an access token, it has to be passed as accessToken so the `ath`
+// claim is checked; the key binding of the access token itself is checked with
+// [RequireDPoPBinding] using the Thumbprint of the returned proof.
+func (v *DPoPVerifier) Verify(proof string, method string, uri string, accessToken string) (*DPoPProof, error) {
+	parts, ok := splitToken(proof)
+	if !ok {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	headerBytes, err := _DecodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	var header struct {
+		Typ string                     `json:"typ"`
+		Alg string                     `json:"alg"`
+		JWK map[string]json.RawMessage `json:"jwk"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	if header.Typ != "dpop+jwt" {
+		return nil, ErrDPoPInvalidType
+	}
+
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultDPoPAlgorithms
+	}
+	if !slices.Contains(algorithms, header.Alg) {
+		return nil, ErrTokenInvalidAlgorithm
+	}
+
+	jwk, err := publicJWK(header.JWK)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := _DecodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	if err := jwk.verifySignature(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claimBytes, err := _DecodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	var claims DPoPClaims
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	if claims.ID == "" || claims.Method == "" || claims.URI == "" || claims.IssuedAt == nil {
+		return nil, ErrDPoPInvalidProof
+	}
+
+	if claims.Method != method {
+		return nil, ErrDPoPMethodMismatch
+	}
+
+	expectedURI, err := normalizeHTU(uri)
+	if err != nil {
+		return nil, ErrDPoPURIMismatch
+	}
+	if actualURI, err := normalizeHTU(claims.URI); err != nil || actualURI != expectedURI {
+		return nil, ErrDPoPURIMismatch
+	}
+
+	if v.ValidNonce != nil && (claims.Nonce == "" || !v.ValidNonce(claims.Nonce)) {
+		return nil, ErrDPoPInvalidNonce
+	}
+
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+
+	maxAge := durationOrDefault(v.MaxAge, defaultDPoPMaxAge)
+	if now.Before(claims.IssuedAt.Add(-v.Leeway)) || now.After(claims.IssuedAt.Add(maxAge)) {
+		return nil, ErrDPoPInvalidIssuedAt
+	}
+
+	if accessToken != "" && subtle.ConstantTimeCompare([]byte(claims.AccessTokenHash), []byte(accessTokenHash(accessToken))) != 1 {
+		return nil, ErrDPoPTokenHashMismatch
+	}
+
+	if v.JTIs != nil {
+		firstUse, err := v.JTIs.Use(claims.ID, claims.IssuedAt.Add(maxAge))
+		if err != nil {
+			return nil, err
+		}
+		if !firstUse {
+			return nil, ErrDPoPReplayed
+		}
+	}
+
+	decodedHeader := make(map[string]any)
+	json.Unmarshal(headerBytes, &decodedHeader)
+
+	return &DPoPProof{Header: decodedHeader, Claims: &claims, JWK: jwk, Thumbprint: jwk.thumbprint()}, nil
+}
+
+// RequireDPoPBinding requires the `cnf.jkt` claim of an access token to match the thumbprint
+// of the DPoP proof which was presented with it, see [DPoPProof.Thumbprint].
+func RequireDPoPBinding(thumbprint string) Validator {
+	return func(ctx *ValidationContext) error {
+		cnf, ok := ClaimValue[Confirmation](ctx, "cnf")
+		if !ok || cnf.JWKThumbprint == "" {
+			return &ClaimError{Claim: "cnf", Err: ErrClaimIsRequired}
+		}
+
+		if subtle.ConstantTimeCompare([]byte(cnf.JWKThumbprint), []byte(thumbprint)) != 1 {
+			return &ClaimError{Claim: "cnf", Err: ErrDPoPKeyMismatch}
+		}
+
+		return nil
+	}
+}
+
+// publicJWK decodes the `jwk` header of a proof, which must not contain a private key.
+func publicJWK(raw map[string]json.RawMessage) (*JWK, error) {
+	if raw == nil {
+		return nil, ErrDPoPInvalidKey
+	}
+
+	for _, private := range []string{"d", "p", "q", "dp", "dq", "qi", "k"} {
+		if _, ok := raw[private]; ok {
+			return nil, ErrDPoPInvalidKey
+		}
+	}
+
+	b, _ := json.Marshal(raw)
+
+	var jwk JWK
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return nil, ErrDPoPInvalidKey
+	}
+
+	return &jwk, nil
+}
+
+// accessTokenHash returns the `ath` value for an access token.
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return _EncodeSegment(sum[:])
+}
+
+// normalizeHTU strips the query and fragment of a request URI and normalizes scheme, host and
+// default ports, so proofs match independent of how the URI was written, see
+// https://datatracker.ietf.org/doc/html/rfc9449#section-4.3.
+func normalizeHTU(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", ErrDPoPURIMismatch
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "https" && port == "443") && !(scheme == "http" && port == "80") {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return scheme + "://" + host + path, nil
+}
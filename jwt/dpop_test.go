@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDPoP_ProofRoundTrip(t *testing.T) {
+	for _, alg := range []string{"EdDSA", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := GenerateDPoPKey(alg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			proof, err := key.Proof("POST", "https://Server.example.com:443/token?foo=bar#frag", "", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			v := NewDPoPVerifier(NewMemoryJTIStore())
+			verified, err := v.Verify(proof, "POST", "https://server.example.com/token", "")
+			if err != nil {
+				t.Fatalf("expected proof to verify, got %v", err)
+			}
+			if verified.Thumbprint != key.Thumbprint() {
+				t.Errorf("expected thumbprint %s, got %s", key.Thumbprint(), verified.Thumbprint)
+			}
+			if verified.Claims.URI != "https://server.example.com/token" {
+				t.Errorf("expected normalized htu, got %s", verified.Claims.URI)
+			}
+
+			if _, err := v.Verify(proof, "POST", "https://server.example.com/token", ""); !errors.Is(err, ErrDPoPReplayed) {
+				t.Errorf("expected ErrDPoPReplayed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDPoPVerifier_Rejects(t *testing.T) {
+	key, _ := GenerateDPoPKey("EdDSA")
+	otherKey, _ := GenerateDPoPKey("EdDSA")
+
+	proof, _ := key.Proof("GET", "https://resource.example.com/api", "access-token", "nonce-1")
+	otherProof, _ := otherKey.Proof("GET", "https://resource.example.com/api", "access-token", "nonce-1")
+	parts := strings.Split(proof, ".")
+	otherParts := strings.Split(otherProof, ".")
+
+	tests := map[string]struct {
+		proof       string
+		method      string
+		uri         string
+		accessToken string
+		configure   func(v *DPoPVerifier)
+		err         error
+	}{
+		"method mismatch":   {proof: proof, method: "POST", err: ErrDPoPMethodMismatch},
+		"uri mismatch":      {proof: proof, uri: "https://resource.example.com/other", err: ErrDPoPURIMismatch},
+		"token mismatch":    {proof: proof, accessToken: "other-token", err: ErrDPoPTokenHashMismatch},
+		"swapped key":       {proof: otherParts[0] + "." + parts[1] + "." + parts[2], err: ErrEd25519Verification},
+		"algorithm":         {proof: proof, configure: func(v *DPoPVerifier) { v.Algorithms = []string{"ES256"} }, err: ErrTokenInvalidAlgorithm},
+		"nonce":             {proof: proof, configure: func(v *DPoPVerifier) { v.ValidNonce = func(n string) bool { return n == "nonce-2" } }, err: ErrDPoPInvalidNonce},
+		"too old":           {proof: proof, configure: func(v *DPoPVerifier) { v.now = func() time.Time { return time.Now().Add(10 * time.Minute) } }, err: ErrDPoPInvalidIssuedAt},
+		"issued in future":  {proof: proof, configure: func(v *DPoPVerifier) { v.now = func() time.Time { return time.Now().Add(-10 * time.Minute) } }, err: ErrDPoPInvalidIssuedAt},
+		"malformed":         {proof: "not-a-jwt", err: ErrDPoPInvalidProof},
+		"access token type": {proof: signTestToken(t, map[string]any{"typ": "JWT", "alg": "EdDSA"}, validTestClaims(), func(string) ([]byte, error) { return nil, nil }), err: ErrDPoPInvalidType},
+		"private key": {
+			proof: _EncodeSegment([]byte(`{"typ":"dpop+jwt","alg":"EdDSA","jwk":{"kty":"OKP","crv":"Ed25519","x":"`+key.JWK().X+`","d":"secret"}}`)) + "." + parts[1] + "." + parts[2],
+			err:   ErrDPoPInvalidKey,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := NewDPoPVerifier(nil)
+			if tt.configure != nil {
+				tt.configure(v)
+			}
+
+			method, uri, accessToken := "GET", "https://resource.example.com/api", "access-token"
+			if tt.method != "" {
+				method = tt.method
+			}
+			if tt.uri != "" {
+				uri = tt.uri
+			}
+			if tt.accessToken != "" {
+				accessToken = tt.accessToken
+			}
+
+			if _, err := v.Verify(tt.proof, method, uri, accessToken); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRequireDPoPBinding(t *testing.T) {
+	type boundClaims struct {
+		Confirmation *Confirmation `json:"cnf,omitempty"`
+		RegisteredClaims
+	}
+
+	key, _ := GenerateDPoPKey("ES256")
+	otherKey, _ := GenerateDPoPKey("ES256")
+
+	claims := &boundClaims{Confirmation: &Confirmation{JWKThumbprint: key.Thumbprint()}, RegisteredClaims: *validTestClaims()}
+
+	if err := validateClaims(claims, &ExpectedClaims{Issuer: "https://issuer.example.com", Audience: []string{"api"}, Validators: []Validator{RequireDPoPBinding(key.Thumbprint())}}); err != nil {
+		t.Errorf("expected binding to match, got %v", err)
+	}
+
+	err := validateClaims(claims, &ExpectedClaims{Issuer: "https://issuer.example.com", Audience: []string{"api"}, Validators: []Validator{RequireDPoPBinding(otherKey.Thumbprint())}})
+	if !errors.Is(err, ErrDPoPKeyMismatch) {
+		t.Errorf("expected ErrDPoPKeyMismatch, got %v", err)
+	}
+
+	claims.Confirmation = nil
+	err = validateClaims(claims, &ExpectedClaims{Issuer: "https://issuer.example.com", Audience: []string{"api"}, Validators: []Validator{RequireDPoPBinding(key.Thumbprint())}})
+	if !errors.Is(err, ErrClaimIsRequired) {
+		t.Errorf("expected ErrClaimIsRequired for unbound token, got %v", err)
+	}
+}
+
+func TestMemoryJTIStore_PrunesExpired(t *testing.T) {
+	store := NewMemoryJTIStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	store.Use("late", now.Add(2*time.Minute))
+	store.Use("early", now.Add(time.Minute))
+
+	if ok, _ := store.Use("early", now.Add(time.Minute)); ok {
+		t.Error("expected replayed jti to be rejected")
+	}
+
+	now = now.Add(90 * time.Second)
+	if ok, _ := store.Use("early", now.Add(time.Minute)); !ok {
+		t.Error("expected expired jti to be usable again")
+	}
+	if ok, _ := store.Use("late", now.Add(time.Minute)); ok {
+		t.Error("expected unexpired jti to be rejected")
+	}
+	if len(store.jtis) != len(store.expiry) || len(store.jtis) != 2 {
+		t.Errorf("expected 2 tracked jtis, got %d in map and %d in heap", len(store.jtis), len(store.expiry))
+	}
+}
+
+func TestDPoPVerifier_StructLiteral(t *testing.T) {
+	key, _ := GenerateDPoPKey("ES256")
+	proof, _ := key.Proof("GET", "https://server.example.com/resource", "", "")
+
+	v := &DPoPVerifier{JTIs: &MemoryJTIStore{}}
+	if _, err := v.Verify(proof, "GET", "https://server.example.com/resource", ""); err != nil {
+		t.Fatalf("expected proof to verify with the defaults, got %v", err)
+	}
+	if _, err := v.Verify(proof, "GET", "https://server.example.com/resource", ""); !errors.Is(err, ErrDPoPReplayed) {
+		t.Errorf("expected ErrDPoPReplayed, got %v", err)
+	}
+}
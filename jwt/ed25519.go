@@ -19,13 +19,17 @@ type PrivateKey ed25519.PrivateKey
 type PublicKey ed25519.PublicKey
 
 func VerifyEd25519(signingString string, sig []byte, key *PublicKey) error {
-	publicKey := ed25519.PublicKey(*key)
+	return verifyEd25519Message([]byte(signingString), sig, key)
+}
 
+// verifyEd25519Message verifies the Ed25519 signature of message, for callers which already
+// hold the signing input as bytes.
+func verifyEd25519Message(message []byte, sig []byte, key *PublicKey) error {
 	if len(*key) != ed25519.PublicKeySize {
 		return ErrInvalidKey
 	}
 
-	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+	if !ed25519.Verify(ed25519.PublicKey(*key), message, sig) {
 		return ErrEd25519Verification
 	}
 
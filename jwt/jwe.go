@@ -0,0 +1,380 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	ErrJWEMalformed         = errors.New("jwt-jwe: token is malformed")
+	ErrJWEInvalidAlgorithm  = errors.New("jwt-jwe: token has an invalid key management or content encryption algorithm")
+	ErrJWEInvalidKey        = errors.New("jwt-jwe: key is invalid for the configured algorithm")
+	ErrJWEDecryption        = errors.New("jwt-jwe: decryption failed")
+	ErrJWEUnsupportedHeader = errors.New("jwt-jwe: token uses an unsupported header parameter")
+	ErrJWEUnexpectedNesting = errors.New("jwt-jwe: token content type does not match the expected nesting")
+	ErrJWEUnauthenticated   = errors.New("jwt-jwe: key algorithm does not authenticate the sender, use a nested signed token")
+)
+
+const (
+	// KeyAlgorithmDir uses SharedKey directly as content encryption key.
+	KeyAlgorithmDir = "dir"
+	// KeyAlgorithmECDHES derives the content encryption key with an ephemeral-static X25519 key agreement.
+	KeyAlgorithmECDHES = "ECDH-ES"
+
+	// EncryptionXC20P is XChaCha20-Poly1305, the same AEAD used by the encryption package.
+	EncryptionXC20P = "XC20P"
+	// EncryptionA256GCM is AES-256-GCM, as defined in https://datatracker.ietf.org/doc/html/rfc7518#section-5.3.
+	EncryptionA256GCM = "A256GCM"
+)
+
+// JWEKey configures the key management (`alg`) and content encryption (`enc`) of a JWE.
+// Like for signatures, there is no algorithm agility: a token is only decrypted if its header
+// matches the configured algorithms exactly.
+//
+// With [KeyAlgorithmDir], SharedKey is a 32 byte key known to both parties. With
+// [KeyAlgorithmECDHES], PublicKey is the X25519 key of the recipient used for encryption and
+// PrivateKey the matching private key used for decryption.
+type JWEKey struct {
+	Algorithm  string
+	Encryption string
+	Kid        string
+	SharedKey  []byte
+	PublicKey  *ecdh.PublicKey
+	PrivateKey *ecdh.PrivateKey
+}
+
+// EncryptToken encrypts the claims as JWE compact serialization, see
+// https://datatracker.ietf.org/doc/html/rfc7516. The claims are only confidential, not signed,
+// so only [KeyAlgorithmDir] is accepted: with ECDH-ES anyone knowing the public key of the
+// recipient could forge tokens. Use [EncryptSignedToken] in that case.
+func EncryptToken[T Claims](claims *T, key *JWEKey) (string, error) {
+	if key.Algorithm != KeyAlgorithmDir {
+		return "", ErrJWEUnauthenticated
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return EncryptJWE(payload, map[string]any{"typ": "JWT"}, key)
+}
+
+// EncryptSignedToken signs the token with signer and encrypts the resulting JWS as nested
+// JWT (`cty: JWT`), see https://datatracker.ietf.org/doc/html/rfc7519#section-5.2.
+func EncryptSignedToken[T Claims](token *Token[T], signer Signer, key *JWEKey) (string, error) {
+	signed, err := token.SignedString(signer)
+	if err != nil {
+		return "", err
+	}
+
+	return EncryptJWE([]byte(signed), map[string]any{"typ": "JWT", "cty": "JWT"}, key)
+}
+
+// DecryptToken decrypts a JWE created by [EncryptToken] and validates its claims the same way
+// as [VerifyToken]. Only [KeyAlgorithmDir] authenticates the sender, keys using ECDH-ES are
+// rejected with ErrJWEUnauthenticated. Nested tokens are rejected, use [DecryptSignedToken]
+// for them.
+func DecryptToken[T Claims](tokenString string, key *JWEKey, expected *ExpectedClaims) (*Token[T], error) {
+	if key.Algorithm != KeyAlgorithmDir {
+		return nil, ErrJWEUnauthenticated
+	}
+
+	header, plaintext, err := DecryptJWE(tokenString, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, nested := header["cty"]; nested {
+		return nil, ErrJWEUnexpectedNesting
+	}
+
+	token := &Token[T]{Raw: tokenString, RawParts: strings.Split(tokenString, "."), Header: header}
+	if err := json.Unmarshal(plaintext, &token.Claims); err != nil || token.Claims == nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := validateTokenClaims(token.Header, *token.Claims, expected); err != nil {
+		return nil, err
+	}
+
+	token.Valid = true
+
+	return token, nil
+}
+
+// DecryptSignedToken decrypts a nested JWT created by [EncryptSignedToken] and verifies the
+// inner Ed25519 JWS with [VerifyToken]. The returned token is the verified inner token.
+func DecryptSignedToken[T Claims](tokenString string, key *JWEKey, verifyKey *PublicKey, expected *ExpectedClaims) (*Token[T], error) {
+	header, plaintext, err := DecryptJWE(tokenString, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if cty, _ := header["cty"].(string); !strings.EqualFold(cty, "JWT") {
+		return nil, ErrJWEUnexpectedNesting
+	}
+
+	return VerifyToken[T](string(plaintext), verifyKey, expected)
+}
+
+// EncryptJWE encrypts an arbitrary payload as JWE compact serialization. The given header
+// parameters are added to the protected header next to `alg`, `enc`, `kid` and `epk`.
+func EncryptJWE(payload []byte, header map[string]any, key *JWEKey) (string, error) {
+	protected := make(map[string]any, len(header)+4)
+	for name, value := range header {
+		protected[name] = value
+	}
+
+	protected["alg"] = key.Algorithm
+	protected["enc"] = key.Encryption
+	if key.Kid != "" {
+		protected["kid"] = key.Kid
+	}
+
+	var cek []byte
+	switch key.Algorithm {
+	case KeyAlgorithmDir:
+		cek = key.SharedKey
+
+	case KeyAlgorithmECDHES:
+		if key.PublicKey == nil || key.PublicKey.Curve() != ecdh.X25519() {
+			return "", ErrJWEInvalidKey
+		}
+
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+
+		shared, err := ephemeral.ECDH(key.PublicKey)
+		if err != nil {
+			return "", ErrJWEInvalidKey
+		}
+
+		cek = concatKDF(shared, key.Encryption, nil, nil, 256)
+		protected["epk"] = NewX25519JWK(ephemeral.PublicKey(), "")
+
+	default:
+		return "", ErrJWEInvalidAlgorithm
+	}
+
+	aead, err := newContentCipher(key.Encryption, cek)
+	if err != nil {
+		return "", err
+	}
+
+	headerBytes, err := json.Marshal(protected)
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := _EncodeSegment(headerBytes)
+
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nil, iv, payload, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return encodedHeader + ".." + _EncodeSegment(iv) + "." + _EncodeSegment(ciphertext) + "." + _EncodeSegment(tag), nil
+}
+
+// DecryptJWE decrypts a JWE compact serialization and returns its protected header and payload.
+func DecryptJWE(tokenString string, key *JWEKey) (map[string]any, []byte, error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 5 {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	headerBytes, err := _DecodeSegment(parts[0])
+	if err != nil {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	var header map[string]any
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	if header["alg"] != key.Algorithm || header["enc"] != key.Encryption {
+		return nil, nil, ErrJWEInvalidAlgorithm
+	}
+
+	if _, ok := header["zip"]; ok {
+		return nil, nil, ErrJWEUnsupportedHeader
+	}
+	if _, ok := header["crit"]; ok {
+		return nil, nil, ErrJWEUnsupportedHeader
+	}
+
+	// Neither dir nor ECDH-ES use an encrypted key.
+	if parts[1] != "" {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	var cek []byte
+	switch key.Algorithm {
+	case KeyAlgorithmDir:
+		cek = key.SharedKey
+
+	case KeyAlgorithmECDHES:
+		if key.PrivateKey == nil || key.PrivateKey.Curve() != ecdh.X25519() {
+			return nil, nil, ErrJWEInvalidKey
+		}
+
+		epk, err := parseEphemeralKey(header["epk"])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shared, err := key.PrivateKey.ECDH(epk)
+		if err != nil {
+			return nil, nil, ErrJWEDecryption
+		}
+
+		cek = concatKDF(shared, key.Encryption, nil, nil, 256)
+
+	default:
+		return nil, nil, ErrJWEInvalidAlgorithm
+	}
+
+	aead, err := newContentCipher(key.Encryption, cek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iv, err := _DecodeSegment(parts[2])
+	if err != nil || len(iv) != aead.NonceSize() {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	ciphertext, err := _DecodeSegment(parts[3])
+	if err != nil {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	tag, err := _DecodeSegment(parts[4])
+	if err != nil || len(tag) != aead.Overhead() {
+		return nil, nil, ErrJWEMalformed
+	}
+
+	plaintext, err := aead.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrJWEDecryption
+	}
+
+	return header, plaintext, nil
+}
+
+// NewX25519JWK creates an `OKP` JWK (https://datatracker.ietf.org/doc/html/rfc8037) for the
+// given X25519 public key, e.g. to publish the key other services should encrypt tokens to.
+func NewX25519JWK(key *ecdh.PublicKey, kid string) *JWK {
+	jwk := &JWK{Kty: "OKP", Crv: "X25519", X: _EncodeSegment(key.Bytes()), Kid: kid}
+	if kid != "" {
+		jwk.Use = "enc"
+	}
+
+	return jwk
+}
+
+// ToX25519PublicKey converts an `OKP` JWK with the `X25519` curve into a public key which
+// can be used as [JWEKey.PublicKey].
+func (jwk *JWK) ToX25519PublicKey() (*ecdh.PublicKey, error) {
+	if jwk.Kty != "OKP" {
+		return nil, ErrInvalidKeyType
+	}
+
+	if jwk.Crv != "X25519" {
+		return nil, ErrInvalidKey
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	key, err := ecdh.X25519().NewPublicKey(xBytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// parseEphemeralKey converts the `epk` header parameter into an X25519 public key.
+func parseEphemeralKey(epk any) (*ecdh.PublicKey, error) {
+	raw, err := json.Marshal(epk)
+	if err != nil {
+		return nil, ErrJWEMalformed
+	}
+
+	var jwk JWK
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return nil, ErrJWEMalformed
+	}
+
+	key, err := jwk.ToX25519PublicKey()
+	if err != nil {
+		return nil, ErrJWEInvalidKey
+	}
+
+	return key, nil
+}
+
+// newContentCipher returns the AEAD for the content encryption algorithm enc keyed with cek.
+func newContentCipher(enc string, cek []byte) (cipher.AEAD, error) {
+	if len(cek) != 32 {
+		return nil, ErrJWEInvalidKey
+	}
+
+	switch enc {
+	case EncryptionXC20P:
+		return chacha20poly1305.NewX(cek)
+	case EncryptionA256GCM:
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+
+	return nil, ErrJWEInvalidAlgorithm
+}
+
+// concatKDF implements the Concat KDF with SHA-256 as used by ECDH-ES in direct key agreement
+// mode, see https://datatracker.ietf.org/doc/html/rfc7518#section-4.6.2. The algorithm ID is
+// the `enc` header value and keyBits the size of the content encryption key.
+func concatKDF(shared []byte, algorithmID string, apu []byte, apv []byte, keyBits int) []byte {
+	lengthPrefixed := func(b []byte) []byte {
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...)
+	}
+
+	otherInfo := lengthPrefixed([]byte(algorithmID))
+	otherInfo = append(otherInfo, lengthPrefixed(apu)...)
+	otherInfo = append(otherInfo, lengthPrefixed(apv)...)
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keyBits))
+
+	key := make([]byte, 0, keyBits/8+sha256.Size)
+	for counter := uint32(1); len(key) < keyBits/8; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(shared)
+		h.Write(otherInfo)
+		key = h.Sum(key)
+	}
+
+	return key[:keyBits/8]
+}
@@ -0,0 +1,205 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestJWEKeys(t *testing.T, enc string) (*JWEKey, *JWEKey) {
+	t.Helper()
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	encrypter := &JWEKey{Algorithm: KeyAlgorithmECDHES, Encryption: enc, Kid: "enc-1", PublicKey: privateKey.PublicKey()}
+	decrypter := &JWEKey{Algorithm: KeyAlgorithmECDHES, Encryption: enc, Kid: "enc-1", PrivateKey: privateKey}
+
+	return encrypter, decrypter
+}
+
+// Example from https://datatracker.ietf.org/doc/html/rfc7518#appendix-C
+func TestConcatKDF_RFC7518Example(t *testing.T) {
+	shared := []byte{158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156, 251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196}
+
+	key := concatKDF(shared, "A128GCM", []byte("Alice"), []byte("Bob"), 128)
+	if _EncodeSegment(key) != "VqqN6vgjbSBcIijNcacQGg" {
+		t.Errorf("unexpected derived key %q", _EncodeSegment(key))
+	}
+}
+
+func newTestSharedJWEKey(enc string) *JWEKey {
+	return &JWEKey{Algorithm: KeyAlgorithmDir, Encryption: enc, Kid: "enc-1", SharedKey: bytes.Repeat([]byte{7}, 32)}
+}
+
+func TestEncryptToken_RoundTrip(t *testing.T) {
+	for _, enc := range []string{EncryptionXC20P, EncryptionA256GCM} {
+		key := newTestSharedJWEKey(enc)
+
+		tokenString, err := EncryptToken(validTestClaims(), key)
+		if err != nil {
+			t.Fatalf("%s: unexpected error encrypting: %v", enc, err)
+		}
+		if strings.Count(tokenString, ".") != 4 {
+			t.Fatalf("%s: expected compact serialization with 5 parts, got %q", enc, tokenString)
+		}
+
+		token, err := DecryptToken[RegisteredClaims](tokenString, key, validTestExpectedClaims())
+		if err != nil {
+			t.Fatalf("%s: unexpected error decrypting: %v", enc, err)
+		}
+		if !token.Valid || token.Claims.Subject != "user-1" {
+			t.Errorf("%s: unexpected token %+v", enc, token)
+		}
+		if token.Header["kid"] != "enc-1" || token.Header["enc"] != enc {
+			t.Errorf("%s: unexpected header %v", enc, token.Header)
+		}
+	}
+}
+
+func TestEncryptToken_Direct(t *testing.T) {
+	key := &JWEKey{Algorithm: KeyAlgorithmDir, Encryption: EncryptionXC20P, SharedKey: bytes.Repeat([]byte{7}, 32)}
+
+	tokenString, err := EncryptToken(validTestClaims(), key)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if _, err := DecryptToken[RegisteredClaims](tokenString, key, validTestExpectedClaims()); err != nil {
+		t.Errorf("unexpected error decrypting: %v", err)
+	}
+
+	wrongKey := &JWEKey{Algorithm: KeyAlgorithmDir, Encryption: EncryptionXC20P, SharedKey: bytes.Repeat([]byte{8}, 32)}
+	if _, err := DecryptToken[RegisteredClaims](tokenString, wrongKey, validTestExpectedClaims()); !errors.Is(err, ErrJWEDecryption) {
+		t.Errorf("expected ErrJWEDecryption, got %v", err)
+	}
+
+	shortKey := &JWEKey{Algorithm: KeyAlgorithmDir, Encryption: EncryptionXC20P, SharedKey: []byte("short")}
+	if _, err := EncryptToken(validTestClaims(), shortKey); !errors.Is(err, ErrJWEInvalidKey) {
+		t.Errorf("expected ErrJWEInvalidKey, got %v", err)
+	}
+}
+
+func TestDecryptToken_RejectsTampering(t *testing.T) {
+	decrypter := newTestSharedJWEKey(EncryptionXC20P)
+	tokenString, _ := EncryptToken(validTestClaims(), decrypter)
+	parts := strings.Split(tokenString, ".")
+
+	ciphertext, _ := _DecodeSegment(parts[3])
+	ciphertext[0] ^= 1
+	tampered := strings.Join([]string{parts[0], parts[1], parts[2], _EncodeSegment(ciphertext), parts[4]}, ".")
+	if _, err := DecryptToken[RegisteredClaims](tampered, decrypter, validTestExpectedClaims()); !errors.Is(err, ErrJWEDecryption) {
+		t.Errorf("expected ErrJWEDecryption for tampered ciphertext, got %v", err)
+	}
+
+	// Changing the protected header invalidates the authentication tag.
+	header := _EncodeSegment([]byte(strings.Replace(string(mustDecodeSegment(t, parts[0])), `"kid":"enc-1"`, `"kid":"enc-2"`, 1)))
+	tampered = strings.Join([]string{header, parts[1], parts[2], parts[3], parts[4]}, ".")
+	if _, err := DecryptToken[RegisteredClaims](tampered, decrypter, validTestExpectedClaims()); !errors.Is(err, ErrJWEDecryption) {
+		t.Errorf("expected ErrJWEDecryption for tampered header, got %v", err)
+	}
+
+	tampered = strings.Join([]string{parts[0], "AAAA", parts[2], parts[3], parts[4]}, ".")
+	if _, err := DecryptToken[RegisteredClaims](tampered, decrypter, validTestExpectedClaims()); !errors.Is(err, ErrJWEMalformed) {
+		t.Errorf("expected ErrJWEMalformed for encrypted key, got %v", err)
+	}
+}
+
+func TestDecryptToken_AlgorithmMismatch(t *testing.T) {
+	tokenString, _ := EncryptToken(validTestClaims(), newTestSharedJWEKey(EncryptionA256GCM))
+	if _, err := DecryptToken[RegisteredClaims](tokenString, newTestSharedJWEKey(EncryptionXC20P), validTestExpectedClaims()); !errors.Is(err, ErrJWEInvalidAlgorithm) {
+		t.Errorf("expected ErrJWEInvalidAlgorithm, got %v", err)
+	}
+}
+
+func TestDecryptToken_RejectsUnauthenticatedKeyAlgorithm(t *testing.T) {
+	encrypter, decrypter := newTestJWEKeys(t, EncryptionXC20P)
+
+	if _, err := EncryptToken(validTestClaims(), encrypter); !errors.Is(err, ErrJWEUnauthenticated) {
+		t.Errorf("expected ErrJWEUnauthenticated encrypting, got %v", err)
+	}
+
+	// Anyone knowing the public key of the recipient can create such a token.
+	tokenString, err := EncryptJWE([]byte(`{"iss":"https://issuer.example.com"}`), map[string]any{"typ": "JWT"}, encrypter)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := DecryptToken[RegisteredClaims](tokenString, decrypter, validTestExpectedClaims()); !errors.Is(err, ErrJWEUnauthenticated) {
+		t.Errorf("expected ErrJWEUnauthenticated decrypting, got %v", err)
+	}
+}
+
+func TestDecryptToken_NullClaims(t *testing.T) {
+	key := newTestSharedJWEKey(EncryptionXC20P)
+
+	tokenString, err := EncryptJWE([]byte("null"), map[string]any{"typ": "JWT"}, key)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := DecryptToken[RegisteredClaims](tokenString, key, validTestExpectedClaims()); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected ErrTokenMalformed, got %v", err)
+	}
+}
+
+func TestEncryptSignedToken_Nested(t *testing.T) {
+	privateKey, _ := ParseEd25519PrivateKey("MC4CAQAwBQYDK2VwBCIEIJ7VP4bGde7HFmugf7wnZ+f09S4wXiHTPqCQB/HYLw+s")
+	publicKey, _ := ParseEd25519PublicKey("MCowBQYDK2VwAyEA7rD1JBNE9qhzXQBN3mltLsAQy34dwDljiSPzmYeqiiM=")
+	encrypter, decrypter := newTestJWEKeys(t, EncryptionXC20P)
+
+	tokenString, err := EncryptSignedToken(NewToken(validTestClaims()), &privateKey, encrypter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token, err := DecryptSignedToken[RegisteredClaims](tokenString, decrypter, &publicKey, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.Header["alg"] != "EdDSA" {
+		t.Errorf("expected inner token to be an Ed25519 JWS, got header %v", token.Header)
+	}
+
+	shared := newTestSharedJWEKey(EncryptionXC20P)
+	nested, _ := EncryptSignedToken(NewToken(validTestClaims()), &privateKey, shared)
+	if _, err := DecryptToken[RegisteredClaims](nested, shared, validTestExpectedClaims()); !errors.Is(err, ErrJWEUnexpectedNesting) {
+		t.Errorf("expected ErrJWEUnexpectedNesting, got %v", err)
+	}
+
+	plain, _ := EncryptToken(validTestClaims(), shared)
+	if _, err := DecryptSignedToken[RegisteredClaims](plain, shared, &publicKey, validTestExpectedClaims()); !errors.Is(err, ErrJWEUnexpectedNesting) {
+		t.Errorf("expected ErrJWEUnexpectedNesting, got %v", err)
+	}
+}
+
+func TestJWK_X25519RoundTrip(t *testing.T) {
+	privateKey, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	jwk := NewX25519JWK(privateKey.PublicKey(), "enc-1")
+	if jwk.Use != "enc" {
+		t.Errorf("expected use 'enc', got %q", jwk.Use)
+	}
+
+	publicKey, err := jwk.ToX25519PublicKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !publicKey.Equal(privateKey.PublicKey()) {
+		t.Error("expected round-tripped key to match")
+	}
+}
+
+func mustDecodeSegment(t *testing.T, segment string) []byte {
+	t.Helper()
+
+	b, err := _DecodeSegment(segment)
+	if err != nil {
+		t.Fatalf("unexpected error decoding segment: %v", err)
+	}
+
+	return b
+}
@@ -1,26 +1,78 @@
 package jwt
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
 )
 
+var (
+	ErrKeyNotFound     = errors.New("jwt: key not found")
+	ErrJWKSUnavailable = errors.New("jwt: JWKS could not be fetched")
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signature verification.
+const minRSAKeyBits = 2048
+
+// maxJWKSSize bounds the size of a fetched JWKS document, which is read into memory.
+const maxJWKSSize = 1 << 20
+
+// JWKSource is implemented by anything that can look up a JSON Web Key by its `kid`.
+// Both a static [JWKS] and a self-refreshing [JWKSCache] satisfy this interface.
+type JWKSource interface {
+	FindKeyByKid(kid string) (*JWK, error)
+}
+
 type JWKS struct {
 	Keys []JWK `json:"keys"`
 }
 
 type JWK struct {
 	Kty string `json:"kty"`
-	N   string `json:"n"`
-	E   string `json:"e"`
-	Use string `json:"use"`
-	Kid string `json:"kid"`
-	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// Private key members, only set for private JWKs, see [JWK.Public].
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+}
+
+// NewEd25519JWK creates an `OKP` JWK (https://datatracker.ietf.org/doc/html/rfc8037) for the
+// given Ed25519 public key, so it can be published as part of a JWKS and used by other services
+// to verify tokens signed with the matching private key. If kid is empty, the JWK SHA-256
+// thumbprint of the key is used as kid, see [JWK.Thumbprint].
+func NewEd25519JWK(key *PublicKey, kid string) (*JWK, error) {
+	if key == nil || len(*key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	jwk := &JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   _EncodeSegment(*key),
+		Use: "sig",
+		Kid: kid,
+		Alg: "EdDSA",
+	}
+
+	return jwk.withDefaultKid(), nil
 }
 
 func (jwk *JWK) ToRSAPublicKey() (*rsa.PublicKey, error) {
@@ -44,35 +96,159 @@ func (jwk *JWK) ToRSAPublicKey() (*rsa.PublicKey, error) {
 	return &rsa.PublicKey{N: n, E: e}, nil
 }
 
+// verifySignature verifies sig over signingString with the public key described by the JWK
+// using the algorithm alg. The key type has to match the algorithm and, if the JWK restricts
+// its `alg` or `use`, these restrictions have to be fulfilled as well.
+func (jwk *JWK) verifySignature(alg string, signingString string, sig []byte) error {
+	if jwk.Alg != "" && jwk.Alg != alg {
+		return ErrTokenInvalidAlgorithm
+	}
+
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return ErrInvalidKey
+	}
+
+	if method := signingMethodRSA(alg); method != nil {
+		if jwk.Kty != "RSA" {
+			return ErrInvalidKeyType
+		}
+
+		key, err := jwk.ToRSAPublicKey()
+		if err != nil {
+			return ErrInvalidKey
+		}
+
+		if key.N.BitLen() < minRSAKeyBits {
+			return ErrInvalidKey
+		}
+
+		return method.VerifyRSA(signingString, sig, key)
+	}
+
+	if method := signingMethodECDSA(alg); method != nil {
+		key, err := jwk.ToECDSAPublicKey()
+		if err != nil {
+			return err
+		}
+
+		return method.VerifyECDSA(signingString, sig, key)
+	}
+
+	if alg == "EdDSA" {
+		key, err := jwk.ToEd25519PublicKey()
+		if err != nil {
+			return err
+		}
+
+		return VerifyEd25519(signingString, sig, &key)
+	}
+
+	return ErrTokenInvalidAlgorithm
+}
+
+// ToECDSAPublicKey converts an `EC` JWK into an ECDSA public key. Only the P-256 and P-384
+// curves are supported and the point has to lie on the curve.
+func (jwk *JWK) ToECDSAPublicKey() (*ecdsa.PublicKey, error) {
+	if jwk.Kty != "EC" {
+		return nil, ErrInvalidKeyType
+	}
+
+	var method *SigningMethodECDSA
+	switch jwk.Crv {
+	case "P-256":
+		method = SigningMethodES256
+	case "P-384":
+		method = SigningMethodES384
+	default:
+		return nil, ErrECDSAInvalidCurve
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+	}
+
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode y coordinate: %w", err)
+	}
+
+	if len(xBytes) != method.KeySize || len(yBytes) != method.KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	point := append(append([]byte{0x04}, xBytes...), yBytes...)
+	key, err := ecdsa.ParseUncompressedPublicKey(method.Curve, point)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// ToEd25519PublicKey converts an `OKP` JWK with the `Ed25519` curve into a public key
+// which can be used with [VerifyToken].
+func (jwk *JWK) ToEd25519PublicKey() (PublicKey, error) {
+	if jwk.Kty != "OKP" {
+		return nil, ErrInvalidKeyType
+	}
+
+	if jwk.Crv != "Ed25519" {
+		return nil, ErrNotEdPublicKey
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	if len(xBytes) != ed25519.PublicKeySize {
+		return nil, ErrNotEdPublicKey
+	}
+
+	return PublicKey(xBytes), nil
+}
+
 func (jwks *JWKS) FindKeyByKid(kid string) (*JWK, error) {
 	for _, key := range jwks.Keys {
 		if key.Kid == kid {
 			return &key, nil
 		}
 	}
-	return nil, fmt.Errorf("key with kid '%s' not found", kid)
+	return nil, fmt.Errorf("%w: key with kid '%s' not found", ErrKeyNotFound, kid)
 }
 
 func FetchJWKS(url string) (*JWKS, error) {
-	resp, err := http.Get(url)
+	jwks, _, err := fetchJWKS(http.DefaultClient, url)
+	return jwks, err
+}
+
+// fetchJWKS downloads and decodes the JWKS document at url using the given client. Next to
+// the parsed key set it returns the response headers so callers can honor caching directives.
+// All errors wrap ErrJWKSUnavailable.
+func fetchJWKS(client *http.Client, url string) (*JWKS, http.Header, error) {
+	resp, err := client.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		return nil, nil, fmt.Errorf("%w: failed to fetch JWKS: %w", ErrJWKSUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("%w: unexpected status code: %d", ErrJWKSUnavailable, resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("%w: failed to read response body: %w", ErrJWKSUnavailable, err)
+	}
+	if len(body) > maxJWKSSize {
+		return nil, nil, fmt.Errorf("%w: response body exceeds %d bytes", ErrJWKSUnavailable, maxJWKSSize)
 	}
 
 	var jwks JWKS
 	if err := json.Unmarshal(body, &jwks); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JWKS: %w", err)
+		return nil, nil, fmt.Errorf("%w: failed to unmarshal JWKS: %w", ErrJWKSUnavailable, err)
 	}
 
-	return &jwks, nil
+	return &jwks, resp.Header, nil
 }
@@ -0,0 +1,306 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The defaults of [NewJWKSCache], which also apply to zero durations of a JWKSCache.
+const (
+	defaultJWKSTTL             = time.Hour
+	defaultJWKSMinTTL          = time.Minute
+	defaultJWKSMaxTTL          = 24 * time.Hour
+	defaultJWKSRefetchInterval = time.Minute
+	defaultJWKSMaxStale        = 6 * time.Hour
+)
+
+// JWKSCache is a concurrency-safe, self-refreshing cache for a remote JWKS document.
+// The lifetime of the cached keys is derived from the `Cache-Control` and `Expires`
+// response headers and clamped between MinTTL and MaxTTL. When a lookup misses (for example
+// because the issuer rotated its keys between two refreshes) the document is fetched again,
+// at most once every RefetchInterval. If a refresh fails, the previously fetched keys are
+// kept and served for up to MaxStale past their expiry, and a later refresh is attempted at
+// most once every RefetchInterval, so an outage of the issuer does not queue every lookup
+// behind a fetch. Once MaxStale has passed, lookups fail with [ErrJWKSUnavailable], as the
+// issuer may have revoked the stale keys in the meantime.
+//
+// A JWKSCache created as a struct literal uses [http.DefaultClient] if Http is nil and the
+// durations of [NewJWKSCache] for zero durations. The exported fields must not be modified
+// after the cache is first used.
+type JWKSCache struct {
+	URL  string
+	Http *http.Client
+
+	// DefaultTTL is used when the response carries no usable caching headers.
+	DefaultTTL time.Duration
+	// MinTTL is the lower bound for the cache lifetime, it protects the issuer from being hammered.
+	MinTTL time.Duration
+	// MaxTTL is the upper bound for the cache lifetime, even if the issuer allows longer caching.
+	MaxTTL time.Duration
+	// RefetchInterval rate-limits refetches triggered by a kid which is not in the cached set
+	// and retries after a failed fetch.
+	RefetchInterval time.Duration
+	// MaxStale limits how long expired keys are served while refreshes fail.
+	MaxStale time.Duration
+
+	mu        sync.RWMutex
+	jwks      *JWKS
+	expiresAt time.Time
+	fetchedAt time.Time
+	failedAt  time.Time
+	fetchErr  error
+
+	fetchMu sync.Mutex
+
+	stopMu sync.Mutex
+	stop   chan struct{}
+
+	now func() time.Time
+}
+
+// NewJWKSCache creates a new JWKSCache for the JWKS document located at url. The cache is
+// lazy: the document is fetched on first use. Call [JWKSCache.Start] to additionally keep
+// the keys fresh in the background.
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		URL:             url,
+		Http:            &http.Client{Timeout: 10 * time.Second},
+		DefaultTTL:      defaultJWKSTTL,
+		MinTTL:          defaultJWKSMinTTL,
+		MaxTTL:          defaultJWKSMaxTTL,
+		RefetchInterval: defaultJWKSRefetchInterval,
+		MaxStale:        defaultJWKSMaxStale,
+		now:             time.Now,
+	}
+}
+
+// JWKS returns the cached key set, fetching it first if it is missing or expired.
+// If a refresh of expired keys fails, the stale keys are returned instead of an error until
+// they are expired for longer than MaxStale.
+func (c *JWKSCache) JWKS() (*JWKS, error) {
+	c.mu.RLock()
+	jwks, expiresAt := c.jwks, c.expiresAt
+	c.mu.RUnlock()
+
+	now := c.clock()
+	if jwks != nil && now.Before(expiresAt) {
+		return jwks, nil
+	}
+
+	if err := c.refresh(false); err != nil {
+		if jwks != nil && now.Before(expiresAt.Add(durationOrDefault(c.MaxStale, defaultJWKSMaxStale))) {
+			return jwks, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jwks, nil
+}
+
+// FindKeyByKid implements the JWKSource interface. If the kid is not part of the cached key
+// set, the JWKS document is fetched again (rate-limited by RefetchInterval) before giving up.
+func (c *JWKSCache) FindKeyByKid(kid string) (*JWK, error) {
+	jwks, err := c.JWKS()
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := jwks.FindKeyByKid(kid)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return jwk, err
+	}
+
+	if err := c.refresh(true); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jwks.FindKeyByKid(kid)
+}
+
+// Refresh unconditionally fetches the JWKS document and replaces the cached keys.
+func (c *JWKSCache) Refresh() error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	return c.fetch()
+}
+
+// Start launches a background goroutine that refreshes the keys shortly before they expire.
+// Calling Start on an already started cache has no effect.
+func (c *JWKSCache) Start() {
+	c.stopMu.Lock()
+	defer c.stopMu.Unlock()
+
+	if c.stop != nil {
+		return
+	}
+
+	c.stop = make(chan struct{})
+	go c.loop(c.stop)
+}
+
+// Stop terminates the background refresh goroutine started by [JWKSCache.Start].
+// The cache itself stays usable and falls back to refreshing lazily.
+func (c *JWKSCache) Stop() {
+	c.stopMu.Lock()
+	defer c.stopMu.Unlock()
+
+	if c.stop == nil {
+		return
+	}
+
+	close(c.stop)
+	c.stop = nil
+}
+
+// loop refreshes the cache until stop is closed. Refreshes are scheduled at 90% of the
+// remaining lifetime; failed refreshes are retried after MinTTL.
+func (c *JWKSCache) loop(stop chan struct{}) {
+	minTTL := durationOrDefault(c.MinTTL, defaultJWKSMinTTL)
+
+	for {
+		wait := minTTL
+
+		if err := c.Refresh(); err == nil {
+			c.mu.RLock()
+			wait = max(c.expiresAt.Sub(c.clock())*9/10, minTTL)
+			c.mu.RUnlock()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh fetches the JWKS document unless another goroutine already did so while this one
+// was waiting. With rateLimited set, a fetch is skipped if the last one happened less than
+// RefetchInterval ago.
+func (c *JWKSCache) refresh(rateLimited bool) error {
+	c.mu.RLock()
+	fetchedAt := c.fetchedAt
+	c.mu.RUnlock()
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.RLock()
+	jwks, lastFetch, expiresAt := c.jwks, c.fetchedAt, c.expiresAt
+	failedAt, fetchErr := c.failedAt, c.fetchErr
+	c.mu.RUnlock()
+
+	// Another goroutine refreshed the keys while we were waiting for the lock.
+	if jwks != nil && lastFetch.After(fetchedAt) {
+		return nil
+	}
+
+	now := c.clock()
+	refetchInterval := durationOrDefault(c.RefetchInterval, defaultJWKSRefetchInterval)
+
+	// The last attempt failed recently, do not hammer the issuer (or block the caller) again.
+	if fetchErr != nil && now.Sub(failedAt) < refetchInterval {
+		return fetchErr
+	}
+
+	if rateLimited && jwks != nil && now.Sub(lastFetch) < refetchInterval {
+		return nil
+	}
+	if !rateLimited && jwks != nil && now.Before(expiresAt) {
+		return nil
+	}
+
+	return c.fetch()
+}
+
+// fetch downloads the JWKS document and stores it. The caller must hold fetchMu.
+func (c *JWKSCache) fetch() error {
+	client := c.Http
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	jwks, header, err := fetchJWKS(client, c.URL)
+	if err != nil {
+		c.mu.Lock()
+		c.failedAt, c.fetchErr = c.clock(), err
+		c.mu.Unlock()
+
+		return err
+	}
+
+	now := c.clock()
+	ttl := cacheLifetime(header, now, durationOrDefault(c.DefaultTTL, defaultJWKSTTL))
+	ttl = min(max(ttl, durationOrDefault(c.MinTTL, defaultJWKSMinTTL)), durationOrDefault(c.MaxTTL, defaultJWKSMaxTTL))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jwks = jwks
+	c.fetchedAt = now
+	c.expiresAt = now.Add(ttl)
+	c.failedAt, c.fetchErr = time.Time{}, nil
+
+	return nil
+}
+
+// clock returns the current time, also for caches which were not created with
+// [NewJWKSCache].
+func (c *JWKSCache) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+
+	return c.now()
+}
+
+// durationOrDefault returns d, or fallback if d is not set.
+func durationOrDefault(d time.Duration, fallback time.Duration) time.Duration {
+	if d == 0 {
+		return fallback
+	}
+
+	return d
+}
+
+// cacheLifetime derives how long a response may be cached from its `Cache-Control`
+// (`max-age` minus `Age`, or `no-cache`/`no-store`) and `Expires` headers. If none of them
+// are present or parsable, fallback is returned.
+func cacheLifetime(header http.Header, now time.Time, fallback time.Duration) time.Duration {
+	for directive := range strings.SplitSeq(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+
+		switch name {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+
+			age, _ := strconv.ParseInt(header.Get("Age"), 10, 64)
+			return time.Duration(max(seconds-age, 0)) * time.Second
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			return max(t.Sub(now), 0)
+		}
+		return 0
+	}
+
+	return fallback
+}
@@ -0,0 +1,302 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newJWKSTestServer serves a JWKS containing a single key whose kid is returned by kid().
+// The number of requests served is counted in hits.
+func newJWKSTestServer(t *testing.T, cacheControl string, kid func() string, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":%q,"n":"AQAB","e":"AQAB"}]}`, kid())
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestJWKSCache_CachesUntilExpired(t *testing.T) {
+	var hits atomic.Int32
+	server := newJWKSTestServer(t, "public, max-age=3600", func() string { return "key-1" }, &hits)
+
+	now := time.Now()
+	cache := NewJWKSCache(server.URL)
+	cache.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := cache.FindKeyByKid("key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected 1 fetch, got %d", hits.Load())
+	}
+
+	now = now.Add(59 * time.Minute)
+	if _, err := cache.JWKS(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected cached keys before max-age, got %d fetches", hits.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.JWKS(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected refetch after max-age, got %d fetches", hits.Load())
+	}
+}
+
+func TestJWKSCache_RefetchOnKidMiss(t *testing.T) {
+	var hits atomic.Int32
+	var current atomic.Value
+	current.Store("key-1")
+	server := newJWKSTestServer(t, "max-age=3600", func() string { return current.Load().(string) }, &hits)
+
+	now := time.Now()
+	cache := NewJWKSCache(server.URL)
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.FindKeyByKid("key-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	current.Store("key-2")
+
+	// Directly after a fetch, misses are rate-limited and must not hit the issuer.
+	if _, err := cache.FindKeyByKid("key-2"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected rate-limited refetch, got %d fetches", hits.Load())
+	}
+
+	now = now.Add(cache.RefetchInterval)
+	jwk, err := cache.FindKeyByKid("key-2")
+	if err != nil {
+		t.Fatalf("expected rotated key to be found, got %v", err)
+	}
+	if jwk.Kid != "key-2" {
+		t.Errorf("expected kid 'key-2', got %q", jwk.Kid)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected 2 fetches, got %d", hits.Load())
+	}
+}
+
+func TestJWKSCache_ServesStaleOnError(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"keys":[{"kty":"RSA","kid":"key-1","n":"AQAB","e":"AQAB"}]}`)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewJWKSCache(server.URL)
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.JWKS(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failing.Store(true)
+	now = now.Add(2 * cache.DefaultTTL)
+
+	if _, err := cache.FindKeyByKid("key-1"); err != nil {
+		t.Errorf("expected stale key to be served, got %v", err)
+	}
+}
+
+func TestJWKSCache_RateLimitsRetriesAfterError(t *testing.T) {
+	var hits atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"keys":[{"kty":"RSA","kid":"key-1","n":"AQAB","e":"AQAB"}]}`)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewJWKSCache(server.URL)
+	cache.now = func() time.Time { return now }
+
+	failing.Store(true)
+	for range 5 {
+		if _, err := cache.JWKS(); err == nil {
+			t.Fatal("expected error while the issuer is failing")
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected 1 fetch while rate-limited, got %d", hits.Load())
+	}
+
+	failing.Store(false)
+	now = now.Add(cache.RefetchInterval)
+	if _, err := cache.FindKeyByKid("key-1"); err != nil {
+		t.Fatalf("expected retry after RefetchInterval to succeed, got %v", err)
+	}
+
+	// Expired keys keep being served without a fetch per lookup while the issuer fails.
+	failing.Store(true)
+	now = now.Add(2 * cache.DefaultTTL)
+	hits.Store(0)
+	for range 5 {
+		if _, err := cache.FindKeyByKid("key-1"); err != nil {
+			t.Errorf("expected stale key to be served, got %v", err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected 1 fetch for expired keys while rate-limited, got %d", hits.Load())
+	}
+}
+
+func TestJWKSCache_ConcurrentLookups(t *testing.T) {
+	var hits atomic.Int32
+	server := newJWKSTestServer(t, "max-age=3600", func() string { return "key-1" }, &hits)
+
+	cache := NewJWKSCache(server.URL)
+
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Go(func() {
+			if _, err := cache.FindKeyByKid("key-1"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Errorf("expected concurrent lookups to share a single fetch, got %d", hits.Load())
+	}
+}
+
+func TestJWKSCache_StartStop(t *testing.T) {
+	var hits atomic.Int32
+	server := newJWKSTestServer(t, "", func() string { return "key-1" }, &hits)
+
+	cache := NewJWKSCache(server.URL)
+	cache.Start()
+	cache.Start()
+	defer cache.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for hits.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected background refresh to fetch once, got %d", hits.Load())
+	}
+
+	if _, err := cache.FindKeyByKid("key-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected lookup to use background-fetched keys, got %d fetches", hits.Load())
+	}
+}
+
+func TestCacheLifetime(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=600"}}, 10 * time.Minute},
+		{"max-age minus age", http.Header{"Cache-Control": {"max-age=600"}, "Age": {"100"}}, 500 * time.Second},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, 0},
+		{"expires", http.Header{"Expires": {now.Add(time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{"max-age wins over expires", http.Header{"Cache-Control": {"max-age=60"}, "Expires": {now.Add(time.Hour).Format(http.TimeFormat)}}, time.Minute},
+		{"invalid expires", http.Header{"Expires": {"0"}}, 0},
+		{"fallback", http.Header{}, 42 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cacheLifetime(tc.header, now, 42*time.Second); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJWKSCache_StopsServingStaleKeysAfterMaxStale(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"keys":[{"kty":"RSA","kid":"key-1","n":"AQAB","e":"AQAB"}]}`)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewJWKSCache(server.URL)
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.JWKS(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failing.Store(true)
+	now = now.Add(cache.DefaultTTL + cache.MaxStale - time.Minute)
+	if _, err := cache.FindKeyByKid("key-1"); err != nil {
+		t.Errorf("expected stale key to be served within MaxStale, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.FindKeyByKid("key-1"); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Errorf("expected ErrJWKSUnavailable after MaxStale, got %v", err)
+	}
+}
+
+func TestJWKSCache_StructLiteral(t *testing.T) {
+	var hits atomic.Int32
+	server := newJWKSTestServer(t, "", func() string { return "key-1" }, &hits)
+
+	cache := &JWKSCache{URL: server.URL}
+	for range 3 {
+		if _, err := cache.FindKeyByKid("key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected keys to be cached for the default TTL, got %d fetches", hits.Load())
+	}
+}
+
+func TestFetchJWKS_LimitsBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"keys":[],"padding":"%s"}`, strings.Repeat("x", maxJWKSSize))
+	}))
+	defer server.Close()
+
+	if _, err := FetchJWKS(server.URL); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Errorf("expected ErrJWKSUnavailable for an oversized document, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewJWKSHandler returns an http.Handler serving the key set returned by keys as a JWKS
+// document, typically mounted at `/.well-known/jwks.json`. The keys function is called on
+// every request so rotated keys are picked up immediately. Responses carry a
+// `Cache-Control` header with the given maxAge and a strong `ETag` derived from the document,
+// conditional requests with a matching `If-None-Match` are answered with 304 Not Modified.
+// Private key members are never served, even if keys returns private JWKs.
+func NewJWKSHandler(keys func() *JWKS, maxAge time.Duration) http.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		public := &JWKS{Keys: []JWK{}}
+		if jwks := keys(); jwks != nil {
+			for _, key := range jwks.Keys {
+				public.Keys = append(public.Keys, *key.Public())
+			}
+		}
+
+		body, err := json.Marshal(public)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", etag)
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	})
+}
+
+// etagMatches reports whether the `If-None-Match` header value matches etag, see
+// https://datatracker.ietf.org/doc/html/rfc9110#section-13.1.2.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKSHandler_ServesKeys(t *testing.T) {
+	publicKey, _ := ParseEd25519PublicKey("MCowBQYDK2VwAyEAIcjUkocF8Vxw6BcY3c8nx1DjgXcCLlqwFfLkma+uJr4=")
+	jwk, err := NewEd25519JWK(&publicKey, "key-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := NewJWKSHandler(func() *JWKS { return &JWKS{Keys: []JWK{*jwk}} }, time.Hour)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("unexpected Cache-Control header %q", got)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("expected ETag header to be set")
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("unexpected error decoding body: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0] != *jwk {
+		t.Errorf("unexpected keys in response: %+v", jwks.Keys)
+	}
+}
+
+func TestJWKSHandler_NotModified(t *testing.T) {
+	handler := NewJWKSHandler(func() *JWKS { return &JWKS{Keys: []JWK{{Kty: "OKP", Kid: "key-1"}}} }, time.Minute)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", rec.Body.String())
+	}
+}
+
+func TestJWKSHandler_EmptyAndMethodNotAllowed(t *testing.T) {
+	handler := NewJWKSHandler(func() *JWKS { return nil }, time.Minute)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Body.String() != `{"keys":[]}` {
+		t.Errorf("expected empty key set, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+}
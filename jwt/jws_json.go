@@ -0,0 +1,274 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+var (
+	ErrTokenNoValidSignature = errors.New("jwt: token has no signature which could be verified with a trusted key")
+)
+
+// JWSSignature is a single signature of a JWS JSON Serialization, see
+// https://datatracker.ietf.org/doc/html/rfc7515#section-7.2.1. Protected is the base64url
+// encoded protected header, Header holds the unprotected header parameters.
+type JWSSignature struct {
+	Protected string         `json:"protected,omitempty"`
+	Header    map[string]any `json:"header,omitempty"`
+	Signature string         `json:"signature"`
+}
+
+// generalJWS is the General JWS JSON Serialization, which carries any number of signatures.
+type generalJWS struct {
+	Payload    string         `json:"payload"`
+	Signatures []JWSSignature `json:"signatures"`
+}
+
+// flattenedJWS is the Flattened JWS JSON Serialization, which carries exactly one signature.
+type flattenedJWS struct {
+	Payload string `json:"payload"`
+	JWSSignature
+}
+
+// jwsJSON accepts both the general and the flattened JWS JSON Serialization.
+type jwsJSON struct {
+	Payload    string         `json:"payload"`
+	Signatures []JWSSignature `json:"signatures"`
+	JWSSignature
+}
+
+// SignedJSON creates and returns the General JWS JSON Serialization of the token with one
+// signature per key. Every signature has its own protected header containing the header of the
+// token and the kid of the key, so the same claims can be verified with any of the keys.
+func (t *Token[T]) SignedJSON(keys ...*SigningKey) (string, error) {
+	if len(keys) == 0 {
+		return "", ErrNoActiveSigningKey
+	}
+
+	payload, err := t.encodedPayload()
+	if err != nil {
+		return "", err
+	}
+
+	jws := generalJWS{Payload: payload, Signatures: make([]JWSSignature, 0, len(keys))}
+	for _, key := range keys {
+		sig, err := t.signJSON(payload, key)
+		if err != nil {
+			return "", err
+		}
+		jws.Signatures = append(jws.Signatures, *sig)
+	}
+
+	out, err := json.Marshal(jws)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// SignedFlattenedJSON creates and returns the Flattened JWS JSON Serialization of the token,
+// signed with key. The kid of the key is stamped into the protected header.
+func (t *Token[T]) SignedFlattenedJSON(key *SigningKey) (string, error) {
+	payload, err := t.encodedPayload()
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := t.signJSON(payload, key)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := json.Marshal(flattenedJWS{Payload: payload, JWSSignature: *sig})
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// SignedJSONWithKeySet creates and returns the General JWS JSON Serialization of the token,
+// signed with every active and retiring key of the key set which still has its private key.
+// During a rotation window the token is therefore verifiable by parties which only trust the
+// old key as well as by parties which already switched to the new one.
+func (t *Token[T]) SignedJSONWithKeySet(ks *KeySet) (string, error) {
+	keys, err := ks.signingKeys()
+	if err != nil {
+		return "", err
+	}
+
+	return t.SignedJSON(keys...)
+}
+
+// encodedPayload returns the base64url encoded claims of the token.
+func (t *Token[T]) encodedPayload() (string, error) {
+	claims, err := json.Marshal(t.Claims)
+	if err != nil {
+		return "", err
+	}
+
+	return _EncodeSegment(claims), nil
+}
+
+// signJSON signs the encoded payload with key, using the header of the token plus the kid of
+// the key as protected header.
+func (t *Token[T]) signJSON(payload string, key *SigningKey) (*JWSSignature, error) {
+	if key == nil || key.signer() == nil {
+		return nil, ErrNotEdPrivateKey
+	}
+	if key.Kid == "" {
+		return nil, ErrKeyMissingKid
+	}
+
+	header := make(map[string]any, len(t.Header)+1)
+	for k, v := range t.Header {
+		header[k] = v
+	}
+	header["kid"] = key.Kid
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	protected := _EncodeSegment(headerBytes)
+
+	sig, err := key.signer().Sign([]byte(protected + "." + payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWSSignature{Protected: protected, Signature: _EncodeSegment(sig)}, nil
+}
+
+// VerifyJSONTokenWithKeySet verifies a token in General or Flattened JWS JSON Serialization
+// against the keys of the key set. The token is valid if any of its signatures carries the kid
+// of an active or retiring key and verifies with it. The claims are validated the same way as
+// in [VerifyToken].
+func VerifyJSONTokenWithKeySet[T Claims](data string, ks *KeySet, expected *ExpectedClaims) (*Token[T], error) {
+	return verifyJSONToken[T](data, ks, []string{"EdDSA"}, expected)
+}
+
+// VerifyJSONTokenWithJWKS verifies a token in General or Flattened JWS JSON Serialization
+// against the keys of jwks. The token is valid if any of its signatures uses an algorithm of
+// the allowlist (only RS256 if the list is empty) and verifies with the key selected by its kid.
+// The claims are validated the same way as in [VerifyToken].
+func VerifyJSONTokenWithJWKS[T Claims](data string, jwks JWKSource, algorithms []string, expected *ExpectedClaims) (*Token[T], error) {
+	if len(algorithms) == 0 {
+		algorithms = []string{SigningMethodRS256.Name}
+	}
+
+	return verifyJSONToken[T](data, jwks, algorithms, expected)
+}
+
+// verifyJSONToken tries every signature of the JWS JSON Serialization until one verifies. If
+// none does, the returned error joins ErrTokenNoValidSignature with the error of every signature.
+func verifyJSONToken[T Claims](data string, jwks JWKSource, algorithms []string, expected *ExpectedClaims) (*Token[T], error) {
+	var jws jwsJSON
+	if err := json.Unmarshal([]byte(data), &jws); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	flattened := jws.JWSSignature.Protected != "" || jws.JWSSignature.Header != nil || jws.JWSSignature.Signature != ""
+	if flattened == (jws.Signatures != nil) {
+		return nil, ErrTokenMalformed
+	}
+
+	signatures := jws.Signatures
+	if flattened {
+		signatures = []JWSSignature{jws.JWSSignature}
+	}
+
+	claimBytes, err := _DecodeSegment(jws.Payload)
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	token := &Token[T]{Raw: data}
+	if err := json.Unmarshal(claimBytes, &token.Claims); err != nil || token.Claims == nil {
+		return nil, ErrTokenMalformed
+	}
+
+	errs := []error{ErrTokenNoValidSignature}
+	for _, sig := range signatures {
+		header, signature, err := verifyJSONSignature(jws.Payload, sig, jwks, algorithms)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		token.RawParts = []string{sig.Protected, jws.Payload, sig.Signature}
+		token.Header = header
+		token.Signature = signature
+		break
+	}
+
+	if token.Header == nil {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := validateTokenClaims(token.Header, *token.Claims, expected); err != nil {
+		return nil, err
+	}
+
+	token.Valid = true
+
+	return token, nil
+}
+
+// verifyJSONSignature verifies a single signature over the encoded payload and returns its
+// protected header together with the decoded signature. The `alg` header has to be
+// protected, the `kid` header may also be unprotected. Unprotected header parameters are not
+// covered by the signature, they are only used to look up the key and never returned.
+func verifyJSONSignature(payload string, sig JWSSignature, jwks JWKSource, algorithms []string) (map[string]any, []byte, error) {
+	headerBytes, err := _DecodeSegment(sig.Protected)
+	if err != nil {
+		return nil, nil, ErrTokenMalformed
+	}
+
+	var protected map[string]any
+	if err := json.Unmarshal(headerBytes, &protected); err != nil {
+		return nil, nil, ErrTokenMalformed
+	}
+
+	alg, ok := protected["alg"].(string)
+	if !ok || !slices.Contains(algorithms, alg) {
+		return nil, nil, ErrTokenInvalidAlgorithm
+	}
+
+	// The protected and unprotected header parameter names have to be disjoint, see
+	// https://datatracker.ietf.org/doc/html/rfc7515#section-7.2.1.
+	for k := range sig.Header {
+		if _, ok := protected[k]; ok {
+			return nil, nil, ErrTokenMalformed
+		}
+	}
+
+	kidValue, ok := protected["kid"]
+	if !ok {
+		kidValue = sig.Header["kid"]
+	}
+
+	kid, ok := kidValue.(string)
+	if !ok || kid == "" {
+		return nil, nil, ErrTokenMissingKid
+	}
+
+	signature, err := _DecodeSegment(sig.Signature)
+	if err != nil {
+		return nil, nil, ErrTokenMalformed
+	}
+
+	jwk, err := jwks.FindKeyByKid(kid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := jwk.verifySignature(alg, sig.Protected+"."+payload, signature); err != nil {
+		return nil, nil, err
+	}
+
+	return protected, signature, nil
+}
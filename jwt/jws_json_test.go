@@ -0,0 +1,192 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignedJSONWithKeySet_RotationWindow(t *testing.T) {
+	oldKey := newTestSigningKey(t, "key-1", KeyStateActive)
+	newKey := newTestSigningKey(t, "key-2", KeyStateActive)
+
+	signer, _ := NewKeySet(oldKey)
+	if err := signer.Rotate(newKey); err != nil {
+		t.Fatalf("unexpected error rotating: %v", err)
+	}
+
+	signed, err := NewToken(validTestClaims()).SignedJSONWithKeySet(signer)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	var jws generalJWS
+	if err := json.Unmarshal([]byte(signed), &jws); err != nil {
+		t.Fatalf("expected general JWS JSON serialization, got %v", err)
+	}
+	if len(jws.Signatures) != 2 {
+		t.Fatalf("expected 2 signatures, got %d", len(jws.Signatures))
+	}
+
+	for _, key := range []SigningKey{oldKey, newKey} {
+		publicKey := PublicKey(ed25519.PrivateKey(*key.PrivateKey).Public().(ed25519.PublicKey))
+		verifier, err := NewKeySet(SigningKey{Kid: key.Kid, PublicKey: &publicKey, State: KeyStateRetiring})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		token, err := VerifyJSONTokenWithKeySet[RegisteredClaims](signed, verifier, validTestExpectedClaims())
+		if err != nil {
+			t.Fatalf("expected token to verify with %s, got %v", key.Kid, err)
+		}
+		if !token.Valid || token.Header["kid"] != key.Kid {
+			t.Errorf("expected valid token verified by %s, got kid %v", key.Kid, token.Header["kid"])
+		}
+	}
+}
+
+func TestSignedFlattenedJSON(t *testing.T) {
+	key := newTestSigningKey(t, "key-1", KeyStateActive)
+	ks, _ := NewKeySet(key)
+
+	signed, err := NewToken(validTestClaims()).SignedFlattenedJSON(&key)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	var jws map[string]any
+	json.Unmarshal([]byte(signed), &jws)
+	if _, ok := jws["signatures"]; ok {
+		t.Errorf("expected flattened serialization without 'signatures', got %s", signed)
+	}
+
+	token, err := VerifyJSONTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected token to verify, got %v", err)
+	}
+	if token.Claims.Subject != "user-1" {
+		t.Errorf("expected subject 'user-1', got %q", token.Claims.Subject)
+	}
+}
+
+func TestVerifyJSONTokenWithKeySet_NoTrustedKey(t *testing.T) {
+	signer, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+	verifier, _ := NewKeySet(newTestSigningKey(t, "key-2", KeyStateActive))
+
+	signed, _ := NewToken(validTestClaims()).SignedJSONWithKeySet(signer)
+
+	_, err := VerifyJSONTokenWithKeySet[RegisteredClaims](signed, verifier, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenNoValidSignature) {
+		t.Errorf("expected ErrTokenNoValidSignature, got %v", err)
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected error to contain ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestVerifyJSONTokenWithKeySet_ForgedKid(t *testing.T) {
+	trusted := newTestSigningKey(t, "key-1", KeyStateActive)
+	attacker := newTestSigningKey(t, "key-1", KeyStateActive)
+	ks, _ := NewKeySet(trusted)
+
+	signed, _ := NewToken(validTestClaims()).SignedJSON(&attacker)
+
+	_, err := VerifyJSONTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims())
+	if !errors.Is(err, ErrEd25519Verification) {
+		t.Errorf("expected ErrEd25519Verification, got %v", err)
+	}
+}
+
+func TestVerifyJSONTokenWithKeySet_TamperedPayload(t *testing.T) {
+	key := newTestSigningKey(t, "key-1", KeyStateActive)
+	ks, _ := NewKeySet(key)
+
+	signed, _ := NewToken(validTestClaims()).SignedJSON(&key)
+
+	var jws generalJWS
+	json.Unmarshal([]byte(signed), &jws)
+
+	claims := validTestClaims()
+	claims.Subject = "admin"
+	claimBytes, _ := json.Marshal(claims)
+	jws.Payload = _EncodeSegment(claimBytes)
+	tampered, _ := json.Marshal(jws)
+
+	if _, err := VerifyJSONTokenWithKeySet[RegisteredClaims](string(tampered), ks, validTestExpectedClaims()); !errors.Is(err, ErrEd25519Verification) {
+		t.Errorf("expected ErrEd25519Verification, got %v", err)
+	}
+}
+
+func TestVerifyJSONTokenWithKeySet_Malformed(t *testing.T) {
+	key := newTestSigningKey(t, "key-1", KeyStateActive)
+	ks, _ := NewKeySet(key)
+
+	signed, _ := NewToken(validTestClaims()).SignedFlattenedJSON(&key)
+
+	var flattened flattenedJWS
+	json.Unmarshal([]byte(signed), &flattened)
+
+	duplicateHeader := flattened
+	duplicateHeader.Header = map[string]any{"kid": "key-1"}
+	duplicateBytes, _ := json.Marshal(duplicateHeader)
+
+	mixed, _ := json.Marshal(map[string]any{
+		"payload":    flattened.Payload,
+		"signature":  flattened.Signature,
+		"signatures": []JWSSignature{flattened.JWSSignature},
+	})
+
+	tests := map[string]struct {
+		data string
+		err  error
+	}{
+		"not json":                   {data: "a.b.c", err: ErrTokenMalformed},
+		"flattened and general":      {data: string(mixed), err: ErrTokenMalformed},
+		"duplicate header parameter": {data: string(duplicateBytes), err: ErrTokenMalformed},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := VerifyJSONTokenWithKeySet[RegisteredClaims](tt.data, ks, validTestExpectedClaims()); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyJSONTokenWithJWKS(t *testing.T) {
+	rsaKey, jwks := newRSATestJWKS(t, "rsa-1")
+	edKey := newTestSigningKey(t, "ed-1", KeyStateActive)
+
+	edSigned, _ := NewToken(validTestClaims()).SignedJSON(&edKey)
+	var jws generalJWS
+	json.Unmarshal([]byte(edSigned), &jws)
+
+	protected := _EncodeSegment([]byte(`{"alg":"RS256"}`))
+	sig, _ := SigningMethodRS256.SignRSA(protected+"."+jws.Payload, rsaKey)
+	jws.Signatures = append(jws.Signatures, JWSSignature{
+		Protected: protected,
+		Header:    map[string]any{"kid": "rsa-1", "typ": "at+jwt"},
+		Signature: _EncodeSegment(sig),
+	})
+	signed, _ := json.Marshal(jws)
+
+	token, err := VerifyJSONTokenWithJWKS[RegisteredClaims](string(signed), jwks, nil, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected token to verify with the RSA signature, got %v", err)
+	}
+	if token.Header["alg"] != "RS256" {
+		t.Errorf("expected RS256 header, got %v", token.Header)
+	}
+	if _, ok := token.Header["kid"]; ok {
+		t.Errorf("expected unprotected header to be left out, got %v", token.Header)
+	}
+
+	// The unprotected typ is not signed and must not satisfy header validators.
+	expected := validTestExpectedClaims()
+	expected.Validators = []Validator{RequireType("at+jwt")}
+	if _, err := VerifyJSONTokenWithJWKS[RegisteredClaims](string(signed), jwks, nil, expected); err == nil {
+		t.Error("expected unprotected typ header to be ignored by validators")
+	}
+}
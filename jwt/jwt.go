@@ -15,6 +15,7 @@ var (
 	ErrInvalidKeyType        = errors.New("jwt: key is of invalid type")
 	ErrTokenMalformed        = errors.New("jwt: token is malformed")
 	ErrTokenInvalidAlgorithm = errors.New("jwt: token has an invalid algorithm")
+	ErrTokenMissingKid       = errors.New("jwt: token has no 'kid' header")
 )
 
 // NumericDate represents a JSON numeric date value, as referenced at
@@ -0,0 +1,359 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrInvalidPEM       = errors.New("jwt: data is not a PEM encoded key")
+	ErrNotRSAPrivateKey = errors.New("jwt-rsa: key is not a valid RSA private key")
+	ErrNotRSAPublicKey  = errors.New("jwt-rsa: key is not a valid RSA public key")
+	ErrRSAKeyTooSmall   = errors.New("jwt-rsa: key is smaller than 2048 bits")
+)
+
+// GenerateEd25519KeyPair generates a new Ed25519 key pair for signing and verifying tokens.
+func GenerateEd25519KeyPair() (PrivateKey, PublicKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return PrivateKey(privateKey), PublicKey(publicKey), nil
+}
+
+// Public returns the public key belonging to the private key.
+func (key PrivateKey) Public() PublicKey {
+	return PublicKey(ed25519.PrivateKey(key).Public().(ed25519.PublicKey))
+}
+
+// EncodeEd25519PrivateKey encodes the private key as base64 PKCS #8 DER, the format accepted by
+// [ParseEd25519PrivateKey].
+func EncodeEd25519PrivateKey(key *PrivateKey) (string, error) {
+	der, err := marshalEd25519PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// EncodeEd25519PublicKey encodes the public key as base64 PKIX DER, the format accepted by
+// [ParseEd25519PublicKey].
+func EncodeEd25519PublicKey(key *PublicKey) (string, error) {
+	der, err := marshalEd25519PublicKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// EncodeEd25519PrivateKeyPEM encodes the private key as PKCS #8 `PRIVATE KEY` PEM block, as
+// written by `openssl genpkey -algorithm ed25519`.
+func EncodeEd25519PrivateKeyPEM(key *PrivateKey) ([]byte, error) {
+	der, err := marshalEd25519PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodeEd25519PublicKeyPEM encodes the public key as PKIX `PUBLIC KEY` PEM block.
+func EncodeEd25519PublicKeyPEM(key *PublicKey) ([]byte, error) {
+	der, err := marshalEd25519PublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParseEd25519PrivateKeyPEM parses a PKCS #8 `PRIVATE KEY` PEM block holding an Ed25519 key.
+func ParseEd25519PrivateKeyPEM(data []byte) (PrivateKey, error) {
+	block, err := decodePEM(data, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pkey, ok := parsedKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	return PrivateKey(pkey), nil
+}
+
+// ParseEd25519PublicKeyPEM parses a PKIX `PUBLIC KEY` PEM block holding an Ed25519 key.
+func ParseEd25519PublicKeyPEM(data []byte) (PublicKey, error) {
+	block, err := decodePEM(data, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pkey, ok := parsedKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrNotEdPublicKey
+	}
+
+	return PublicKey(pkey), nil
+}
+
+// NewEd25519PrivateJWK creates an `OKP` JWK including the private key `d`, see
+// https://datatracker.ietf.org/doc/html/rfc8037#section-2. It must never be published, use
+// [NewEd25519JWK] or [JWK.Public] for that. An empty kid is derived as in [NewEd25519JWK].
+func NewEd25519PrivateJWK(key *PrivateKey, kid string) (*JWK, error) {
+	if key == nil || len(*key) != ed25519.PrivateKeySize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	publicKey := key.Public()
+
+	jwk, err := NewEd25519JWK(&publicKey, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk.D = _EncodeSegment(ed25519.PrivateKey(*key).Seed())
+
+	return jwk, nil
+}
+
+// ToEd25519PrivateKey converts an `OKP` JWK with the `Ed25519` curve and a private key `d`
+// into a private key. The public key `x` has to belong to `d`.
+func (jwk *JWK) ToEd25519PrivateKey() (PrivateKey, error) {
+	publicKey, err := jwk.ToEd25519PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	key := PrivateKey(ed25519.NewKeyFromSeed(seed))
+	if !key.Public().Equal(publicKey) {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	return key, nil
+}
+
+// Equal reports whether both public keys are the same.
+func (key PublicKey) Equal(other PublicKey) bool {
+	return ed25519.PublicKey(key).Equal(ed25519.PublicKey(other))
+}
+
+// GenerateRSAKey generates a new RSA private key for [SigningMethodRSA]. Keys smaller than
+// 2048 bits are rejected, as they are not accepted for verification either.
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	if bits < minRSAKeyBits {
+		return nil, ErrRSAKeyTooSmall
+	}
+
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// EncodeRSAPrivateKeyPEM encodes the private key as PKCS #8 `PRIVATE KEY` PEM block.
+func EncodeRSAPrivateKeyPEM(key *rsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodeRSAPublicKeyPEM encodes the public key as PKIX `PUBLIC KEY` PEM block.
+func EncodeRSAPublicKeyPEM(key *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParseRSAPrivateKeyPEM parses a PKCS #8 `PRIVATE KEY` or PKCS #1 `RSA PRIVATE KEY` PEM block.
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, err := decodePEM(data, "PRIVATE KEY", "RSA PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pkey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAPrivateKey
+	}
+
+	return pkey, nil
+}
+
+// ParseRSAPublicKeyPEM parses a PKIX `PUBLIC KEY` or PKCS #1 `RSA PUBLIC KEY` PEM block.
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, err := decodePEM(data, "PUBLIC KEY", "RSA PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	parsedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pkey, ok := parsedKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrNotRSAPublicKey
+	}
+
+	return pkey, nil
+}
+
+// NewRSAJWK creates an `RSA` JWK for the public key, restricted to signatures with alg. If kid
+// is empty, the JWK SHA-256 thumbprint of the key is used as kid, see [JWK.Thumbprint].
+func NewRSAJWK(key *rsa.PublicKey, kid string, alg string) (*JWK, error) {
+	if key == nil || key.N == nil {
+		return nil, ErrNotRSAPublicKey
+	}
+
+	jwk := &JWK{
+		Kty: "RSA",
+		N:   _EncodeSegment(key.N.Bytes()),
+		E:   _EncodeSegment(big.NewInt(int64(key.E)).Bytes()),
+		Use: "sig",
+		Kid: kid,
+		Alg: alg,
+	}
+
+	return jwk.withDefaultKid(), nil
+}
+
+// NewRSAPrivateJWK creates an `RSA` JWK including all private key members, see
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6.3.2. It must never be published,
+// use [NewRSAJWK] or [JWK.Public] for that. An empty kid is derived as in [NewRSAJWK].
+func NewRSAPrivateJWK(key *rsa.PrivateKey, kid string, alg string) (*JWK, error) {
+	if key == nil || len(key.Primes) != 2 {
+		return nil, ErrNotRSAPrivateKey
+	}
+
+	jwk, err := NewRSAJWK(&key.PublicKey, kid, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Precompute()
+
+	jwk.D = _EncodeSegment(key.D.Bytes())
+	jwk.P = _EncodeSegment(key.Primes[0].Bytes())
+	jwk.Q = _EncodeSegment(key.Primes[1].Bytes())
+	jwk.DP = _EncodeSegment(key.Precomputed.Dp.Bytes())
+	jwk.DQ = _EncodeSegment(key.Precomputed.Dq.Bytes())
+	jwk.QI = _EncodeSegment(key.Precomputed.Qinv.Bytes())
+
+	return jwk, nil
+}
+
+// ToRSAPrivateKey converts an `RSA` JWK with its private members into a private key. The key
+// is validated, so a JWK whose members do not belong together is rejected.
+func (jwk *JWK) ToRSAPrivateKey() (*rsa.PrivateKey, error) {
+	if jwk.Kty != "RSA" {
+		return nil, ErrInvalidKeyType
+	}
+
+	publicKey, err := jwk.ToRSAPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*big.Int, 3)
+	for i, member := range []string{jwk.D, jwk.P, jwk.Q} {
+		b, err := base64.RawURLEncoding.DecodeString(member)
+		if err != nil || len(b) == 0 {
+			return nil, ErrNotRSAPrivateKey
+		}
+		members[i] = new(big.Int).SetBytes(b)
+	}
+
+	key := &rsa.PrivateKey{PublicKey: *publicKey, D: members[0], Primes: []*big.Int{members[1], members[2]}}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotRSAPrivateKey, err)
+	}
+
+	key.Precompute()
+
+	return key, nil
+}
+
+// Public returns a copy of the JWK without any private key members, safe to be published.
+func (jwk *JWK) Public() *JWK {
+	public := *jwk
+	public.D, public.P, public.Q, public.DP, public.DQ, public.QI = "", "", "", "", "", ""
+
+	return &public
+}
+
+// marshalEd25519PrivateKey returns the PKCS #8 DER encoding of the private key.
+func marshalEd25519PrivateKey(key *PrivateKey) ([]byte, error) {
+	if key == nil || len(*key) != ed25519.PrivateKeySize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	return x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(*key))
+}
+
+// marshalEd25519PublicKey returns the PKIX DER encoding of the public key.
+func marshalEd25519PublicKey(key *PublicKey) ([]byte, error) {
+	if key == nil || len(*key) != ed25519.PublicKeySize {
+		return nil, ErrNotEdPublicKey
+	}
+
+	return x509.MarshalPKIXPublicKey(ed25519.PublicKey(*key))
+}
+
+// decodePEM decodes the first PEM block of data, which has to be of one of the given types.
+func decodePEM(data []byte, types ...string) (*pem.Block, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	for _, t := range types {
+		if block.Type == t {
+			return block, nil
+		}
+	}
+
+	return nil, ErrInvalidPEM
+}
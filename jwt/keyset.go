@@ -0,0 +1,312 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoActiveSigningKey = errors.New("jwt: key set has no active signing key")
+	ErrKeyUnusable        = errors.New("jwt: key is revoked or outside of its validity window")
+	ErrKeyMissingKid      = errors.New("jwt: key has no kid")
+	ErrKeyDuplicateKid    = errors.New("jwt: key with the same kid already exists")
+)
+
+// KeyState describes how a key in a [KeySet] may be used.
+type KeyState int
+
+const (
+	// KeyStateActive keys sign new tokens and verify existing ones.
+	KeyStateActive KeyState = iota
+	// KeyStateRetiring keys no longer sign new tokens but still verify tokens issued before the rotation.
+	KeyStateRetiring
+	// KeyStateRevoked keys are neither used for signing nor for verification.
+	KeyStateRevoked
+)
+
+// String returns the string representation of the KeyState.
+func (s KeyState) String() string {
+	switch s {
+	case KeyStateActive:
+		return "active"
+	case KeyStateRetiring:
+		return "retiring"
+	case KeyStateRevoked:
+		return "revoked"
+	}
+
+	return fmt.Sprintf("KeyState(%d)", s)
+}
+
+// SigningKey is an Ed25519 key pair identified by its kid. The private key is either held in
+// memory as PrivateKey or kept in external custody behind Signer, in which case PrivateKey is
+// nil. Verify-only keys, for example keys of another instance or keys whose private part was
+// already destroyed, have neither.
+// A key is only used while the current time is within NotBefore and NotAfter, a zero value
+// means the window is unbounded on that side.
+type SigningKey struct {
+	Kid        string
+	PrivateKey *PrivateKey
+	Signer     Signer
+	PublicKey  *PublicKey
+	State      KeyState
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+// signer returns the Signer of the key, or nil for verify-only keys.
+func (k *SigningKey) signer() Signer {
+	if k.PrivateKey != nil {
+		return k.PrivateKey
+	}
+
+	return k.Signer
+}
+
+// usableAt reports whether the key is not revoked and t is within its validity window.
+func (k *SigningKey) usableAt(t time.Time) bool {
+	if k.State == KeyStateRevoked {
+		return false
+	}
+
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+
+	if !k.NotAfter.IsZero() && t.After(k.NotAfter) {
+		return false
+	}
+
+	return true
+}
+
+// KeySet holds the Ed25519 keys of an issuer and allows rotating them without invalidating
+// tokens which are still in flight: the active key signs new tokens and stamps its kid into
+// the token header, while retiring keys keep verifying the tokens they signed before.
+// A KeySet is safe for concurrent use.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*SigningKey
+	now  func() time.Time
+}
+
+// NewKeySet creates a new KeySet containing the given keys.
+func NewKeySet(keys ...SigningKey) (*KeySet, error) {
+	ks := &KeySet{now: time.Now}
+
+	for _, key := range keys {
+		if err := ks.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+// Add adds a key to the set. If the key has no PublicKey it is derived from its PrivateKey or
+// Signer. Active keys must have a PrivateKey or Signer, and the kid must be unique within the set.
+func (ks *KeySet) Add(key SigningKey) error {
+	if key.Kid == "" {
+		return ErrKeyMissingKid
+	}
+
+	if key.PrivateKey != nil {
+		if len(*key.PrivateKey) != ed25519.PrivateKeySize {
+			return ErrNotEdPrivateKey
+		}
+
+		if key.PublicKey == nil {
+			publicKey := key.PrivateKey.Public()
+			key.PublicKey = &publicKey
+		}
+	} else if key.Signer != nil && key.PublicKey == nil {
+		publicKey := key.Signer.Public()
+		key.PublicKey = &publicKey
+	}
+
+	if key.PublicKey == nil || len(*key.PublicKey) != ed25519.PublicKeySize {
+		return ErrNotEdPublicKey
+	}
+
+	if key.State == KeyStateActive && key.signer() == nil {
+		return ErrNotEdPrivateKey
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.find(key.Kid) != nil {
+		return ErrKeyDuplicateKid
+	}
+
+	ks.keys = append(ks.keys, &key)
+
+	return nil
+}
+
+// Rotate adds key as the new active key and moves all currently active keys to the
+// retiring state, so they keep verifying tokens until they are revoked or expire.
+// If the NotBefore of key lies in the future, the current keys stay active and keep signing
+// until key becomes valid, after which [KeySet.SigningKey] prefers key as the newest one.
+// Call Rotate again or use [KeySet.SetState] to retire them from then on.
+func (ks *KeySet) Rotate(key SigningKey) error {
+	key.State = KeyStateActive
+	if err := ks.Add(key); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if !key.usableAt(ks.now()) {
+		return nil
+	}
+
+	for _, k := range ks.keys {
+		if k.Kid != key.Kid && k.State == KeyStateActive {
+			k.State = KeyStateRetiring
+		}
+	}
+
+	return nil
+}
+
+// SetState changes the state of the key identified by kid.
+func (ks *KeySet) SetState(kid string, state KeyState) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := ks.find(kid)
+	if key == nil {
+		return ErrKeyNotFound
+	}
+
+	if state == KeyStateActive && key.signer() == nil {
+		return ErrNotEdPrivateKey
+	}
+
+	key.State = state
+
+	return nil
+}
+
+// Remove deletes the key identified by kid from the set.
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys = slices.DeleteFunc(ks.keys, func(k *SigningKey) bool { return k.Kid == kid })
+}
+
+// SigningKey returns a copy of the key used to sign new tokens. If several active keys are
+// usable, the most recently added one wins.
+func (ks *KeySet) SigningKey() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := ks.now()
+	for _, key := range slices.Backward(ks.keys) {
+		if key.State == KeyStateActive && key.usableAt(now) {
+			k := *key
+			return &k, nil
+		}
+	}
+
+	return nil, ErrNoActiveSigningKey
+}
+
+// signingKeys returns copies of all keys which may currently sign, the active keys followed by
+// the retiring keys which still have their private key.
+func (ks *KeySet) signingKeys() ([]*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var active, retiring []*SigningKey
+
+	now := ks.now()
+	for _, key := range slices.Backward(ks.keys) {
+		if key.signer() == nil || !key.usableAt(now) {
+			continue
+		}
+
+		k := *key
+		switch key.State {
+		case KeyStateActive:
+			active = append(active, &k)
+		case KeyStateRetiring:
+			retiring = append(retiring, &k)
+		}
+	}
+
+	if len(active) == 0 {
+		return nil, ErrNoActiveSigningKey
+	}
+
+	return append(active, retiring...), nil
+}
+
+// VerificationKey returns the public key identified by kid if it may currently be used to
+// verify tokens, which is the case for active and retiring keys within their validity window.
+func (ks *KeySet) VerificationKey(kid string) (*PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key := ks.find(kid)
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	if !key.usableAt(ks.now()) {
+		return nil, ErrKeyUnusable
+	}
+
+	return key.PublicKey, nil
+}
+
+// JWKS returns the public keys which may currently be used for verification as a JWKS, ready
+// to be served with [NewJWKSHandler].
+func (ks *KeySet) JWKS() *JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := &JWKS{Keys: []JWK{}}
+
+	now := ks.now()
+	for _, key := range ks.keys {
+		if !key.usableAt(now) {
+			continue
+		}
+
+		if jwk, err := NewEd25519JWK(key.PublicKey, key.Kid); err == nil {
+			jwks.Keys = append(jwks.Keys, *jwk)
+		}
+	}
+
+	return jwks
+}
+
+// FindKeyByKid implements the JWKSource interface for keys which may currently be used for
+// verification.
+func (ks *KeySet) FindKeyByKid(kid string) (*JWK, error) {
+	publicKey, err := ks.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEd25519JWK(publicKey, kid)
+}
+
+// find returns the key identified by kid or nil. The caller must hold the lock.
+func (ks *KeySet) find(kid string) *SigningKey {
+	for _, key := range ks.keys {
+		if key.Kid == kid {
+			return key
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestSigningKey(t *testing.T, kid string, state KeyState) SigningKey {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	key := PrivateKey(privateKey)
+	return SigningKey{Kid: kid, PrivateKey: &key, State: state}
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	ks, err := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token := NewToken(validTestClaims())
+	signed, err := token.SignedStringWithKeySet(ks)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	parsed, err := VerifyTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if parsed.Header["kid"] != "key-1" {
+		t.Errorf("expected kid 'key-1' in header, got %v", parsed.Header["kid"])
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	ks, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+
+	oldToken, _ := NewToken(validTestClaims()).SignedStringWithKeySet(ks)
+
+	if err := ks.Rotate(newTestSigningKey(t, "key-2", KeyStateRetiring)); err != nil {
+		t.Fatalf("unexpected error rotating: %v", err)
+	}
+
+	newToken, _ := NewToken(validTestClaims()).SignedStringWithKeySet(ks)
+	parsed, err := VerifyTokenWithKeySet[RegisteredClaims](newToken, ks, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected new token to verify, got %v", err)
+	}
+	if parsed.Header["kid"] != "key-2" {
+		t.Errorf("expected new token to be signed with 'key-2', got %v", parsed.Header["kid"])
+	}
+
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](oldToken, ks, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected token of retiring key to verify, got %v", err)
+	}
+
+	if err := ks.SetState("key-1", KeyStateRevoked); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](oldToken, ks, validTestExpectedClaims()); !errors.Is(err, ErrKeyUnusable) {
+		t.Errorf("expected ErrKeyUnusable for revoked key, got %v", err)
+	}
+
+	if jwks := ks.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "key-2" {
+		t.Errorf("expected only 'key-2' to be published, got %+v", jwks.Keys)
+	}
+
+	ks.Remove("key-1")
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](oldToken, ks, validTestExpectedClaims()); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound for removed key, got %v", err)
+	}
+}
+
+func TestKeySet_ValidityWindow(t *testing.T) {
+	now := time.Now()
+
+	key := newTestSigningKey(t, "key-1", KeyStateActive)
+	key.NotBefore = now.Add(time.Hour)
+
+	ks, _ := NewKeySet(key)
+	if _, err := ks.SigningKey(); !errors.Is(err, ErrNoActiveSigningKey) {
+		t.Errorf("expected ErrNoActiveSigningKey before NotBefore, got %v", err)
+	}
+
+	ks.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, err := ks.SigningKey(); err != nil {
+		t.Errorf("expected key to be usable within its window, got %v", err)
+	}
+
+	expired := newTestSigningKey(t, "key-2", KeyStateRetiring)
+	expired.NotAfter = now.Add(time.Hour)
+	ks.Add(expired)
+
+	if _, err := ks.VerificationKey("key-2"); !errors.Is(err, ErrKeyUnusable) {
+		t.Errorf("expected ErrKeyUnusable after NotAfter, got %v", err)
+	}
+}
+
+func TestKeySet_VerifyOnlyKey(t *testing.T) {
+	signing := newTestSigningKey(t, "key-1", KeyStateActive)
+	ks, _ := NewKeySet(signing)
+	signed, _ := NewToken(validTestClaims()).SignedStringWithKeySet(ks)
+
+	publicKey := PublicKey(ed25519.PrivateKey(*signing.PrivateKey).Public().(ed25519.PublicKey))
+	verifier, _ := NewKeySet(SigningKey{Kid: "key-1", PublicKey: &publicKey, State: KeyStateRetiring})
+
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](signed, verifier, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected verify-only key to verify, got %v", err)
+	}
+	if _, err := verifier.SigningKey(); !errors.Is(err, ErrNoActiveSigningKey) {
+		t.Errorf("expected ErrNoActiveSigningKey, got %v", err)
+	}
+	if err := verifier.SetState("key-1", KeyStateActive); !errors.Is(err, ErrNotEdPrivateKey) {
+		t.Errorf("expected ErrNotEdPrivateKey when activating a verify-only key, got %v", err)
+	}
+}
+
+func TestKeySet_AddValidation(t *testing.T) {
+	ks, _ := NewKeySet()
+
+	if err := ks.Add(SigningKey{}); !errors.Is(err, ErrKeyMissingKid) {
+		t.Errorf("expected ErrKeyMissingKid, got %v", err)
+	}
+	if err := ks.Add(SigningKey{Kid: "key-1", State: KeyStateRetiring}); !errors.Is(err, ErrNotEdPublicKey) {
+		t.Errorf("expected ErrNotEdPublicKey, got %v", err)
+	}
+	if err := ks.Add(newTestSigningKey(t, "key-1", KeyStateActive)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ks.Add(newTestSigningKey(t, "key-1", KeyStateActive)); !errors.Is(err, ErrKeyDuplicateKid) {
+		t.Errorf("expected ErrKeyDuplicateKid, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithKeySet_MissingKid(t *testing.T) {
+	ks, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+	key, _ := ks.SigningKey()
+
+	signed, _ := NewToken(validTestClaims()).SignedString(key.PrivateKey)
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims()); !errors.Is(err, ErrTokenMissingKid) {
+		t.Errorf("expected ErrTokenMissingKid, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithKeySet_NullClaims(t *testing.T) {
+	ks, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+	key, _ := ks.SigningKey()
+
+	signed := signTestToken(t, map[string]any{"alg": "EdDSA", "kid": "key-1"}, nil, func(s string) ([]byte, error) {
+		return key.PrivateKey.Sign([]byte(s))
+	})
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims()); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected ErrTokenMalformed, got %v", err)
+	}
+}
+
+func TestKeySet_RotateKeepsCurrentKeyUntilNewKeyIsValid(t *testing.T) {
+	ks, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+
+	now := time.Now()
+	ks.now = func() time.Time { return now }
+
+	next := newTestSigningKey(t, "key-2", KeyStateActive)
+	next.NotBefore = now.Add(time.Hour)
+	if err := ks.Rotate(next); err != nil {
+		t.Fatalf("unexpected error rotating: %v", err)
+	}
+
+	key, err := ks.SigningKey()
+	if err != nil {
+		t.Fatalf("expected a signing key before the new key is valid, got %v", err)
+	}
+	if key.Kid != "key-1" {
+		t.Errorf("expected 'key-1' to keep signing, got %q", key.Kid)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if key, err = ks.SigningKey(); err != nil || key.Kid != "key-2" {
+		t.Errorf("expected 'key-2' to sign once valid, got %v, %v", key, err)
+	}
+}
+
+func TestKeyState_StringUnknown(t *testing.T) {
+	if got := KeyState(42).String(); got != "KeyState(42)" {
+		t.Errorf("expected 'KeyState(42)', got %q", got)
+	}
+}
+
+func TestKeySet_SignedStringWithNilHeader(t *testing.T) {
+	ks, _ := NewKeySet(newTestSigningKey(t, "key-1", KeyStateActive))
+
+	token := &Token[RegisteredClaims]{Claims: validTestClaims()}
+	signed, err := token.SignedStringWithKeySet(ks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected token to verify, got %v", err)
+	}
+	if token.Header["kid"] != "key-1" {
+		t.Errorf("expected kid 'key-1' in header, got %v", token.Header["kid"])
+	}
+}
@@ -0,0 +1,203 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MapClaims is a dynamic claims type for tokens whose claims are not known at compile time,
+// e.g. in a proxy handling tokens of many issuers. It can be used with every function taking a
+// Claims type parameter, such as [VerifyToken] and [UnsecureDecodeToken]. Decoded numbers are
+// kept as json.Number, so integers do not lose precision, and the registered claims have to
+// be of their registered type, otherwise the token is malformed.
+type MapClaims map[string]any
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MapClaims) UnmarshalJSON(b []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var claims map[string]any
+	if err := decoder.Decode(&claims); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		if value, ok := claims[name]; ok && value != nil {
+			if _, ok := toNumericDate(value); !ok {
+				return fmt.Errorf("could not parse claim '%s' as NumericDate", name)
+			}
+		}
+	}
+
+	for _, name := range []string{"iss", "sub", "jti"} {
+		if value, ok := claims[name]; ok && value != nil {
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("could not parse claim '%s' as string", name)
+			}
+		}
+	}
+
+	if value, ok := claims["aud"]; ok && value != nil {
+		if _, ok := toStringSlice(value); !ok {
+			return fmt.Errorf("could not parse Audience: %v", value)
+		}
+	}
+
+	*m = claims
+	return nil
+}
+
+// String returns the claim with the given name if it is a string.
+func (m MapClaims) String(name string) (string, bool) {
+	value, ok := m[name].(string)
+	return value, ok
+}
+
+// Int64 returns the claim with the given name if it is an integral number which fits into an
+// int64.
+func (m MapClaims) Int64(name string) (int64, bool) {
+	switch value := m[name].(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, true
+		}
+		f, err := value.Float64()
+		return int64(f), err == nil && isInt64(f)
+	case float64:
+		return int64(value), isInt64(value)
+	case int:
+		return int64(value), true
+	case int64:
+		return value, true
+	}
+
+	return 0, false
+}
+
+// StringSlice returns the claim with the given name if it is an array of strings. A single
+// string is returned as one element slice, as in the `aud` claim.
+func (m MapClaims) StringSlice(name string) ([]string, bool) {
+	return toStringSlice(m[name])
+}
+
+// Time returns the claim with the given name if it is a NumericDate, the number of seconds
+// since the UNIX epoch.
+func (m MapClaims) Time(name string) (time.Time, bool) {
+	date, ok := toNumericDate(m[name])
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return date.Time, true
+}
+
+// GetIssuer implements the Claims interface.
+func (m MapClaims) GetIssuer() string {
+	issuer, _ := m.String("iss")
+	return issuer
+}
+
+// GetSubject implements the Claims interface.
+func (m MapClaims) GetSubject() string {
+	subject, _ := m.String("sub")
+	return subject
+}
+
+// GetAudience implements the Claims interface.
+func (m MapClaims) GetAudience() Audience {
+	audience, _ := m.StringSlice("aud")
+	return audience
+}
+
+// GetExpirationTime implements the Claims interface.
+func (m MapClaims) GetExpirationTime() *NumericDate {
+	date, _ := toNumericDate(m["exp"])
+	return date
+}
+
+// GetNotBefore implements the Claims interface.
+func (m MapClaims) GetNotBefore() *NumericDate {
+	date, _ := toNumericDate(m["nbf"])
+	return date
+}
+
+// GetIssuedAt implements the Claims interface.
+func (m MapClaims) GetIssuedAt() *NumericDate {
+	date, _ := toNumericDate(m["iat"])
+	return date
+}
+
+// GetID implements the Claims interface.
+func (m MapClaims) GetID() string {
+	id, _ := m.String("jti")
+	return id
+}
+
+// toNumericDate converts a decoded number, or a date set by the caller, into a NumericDate.
+func toNumericDate(value any) (*NumericDate, bool) {
+	var seconds float64
+
+	switch v := value.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, false
+		}
+		seconds = f
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	case NumericDate:
+		return &v, true
+	case *NumericDate:
+		return v, v != nil
+	case time.Time:
+		return &NumericDate{v}, true
+	default:
+		return nil, false
+	}
+
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, false
+	}
+
+	round, frac := math.Modf(seconds)
+	return &NumericDate{time.Unix(int64(round), int64(frac*1e9)).Truncate(time.Second)}, true
+}
+
+// toStringSlice converts a string or an array of strings into a slice.
+func toStringSlice(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case Audience:
+		return v, true
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	}
+
+	return nil, false
+}
+
+// isInt64 reports whether f is an integer within the range of int64.
+func isInt64(f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
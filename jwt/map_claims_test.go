@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMapClaims_VerifyToken(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	now := time.Now()
+	claims := &MapClaims{
+		"iss":    "https://issuer.example.com",
+		"sub":    "user-1",
+		"aud":    "api",
+		"exp":    now.Add(time.Hour).Unix(),
+		"nbf":    now.Unix(),
+		"iat":    &NumericDate{now},
+		"tenant": "acme",
+		"roles":  []string{"admin", "user"},
+		"quota":  int64(9007199254740993),
+	}
+
+	signed, err := NewToken(claims).SignedString(&privateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	token, err := VerifyToken[MapClaims](signed, &publicKey, validTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected token to verify, got %v", err)
+	}
+
+	parsed := *token.Claims
+	if tenant, ok := parsed.String("tenant"); !ok || tenant != "acme" {
+		t.Errorf("unexpected tenant %q", tenant)
+	}
+	if roles, ok := parsed.StringSlice("roles"); !ok || len(roles) != 2 || roles[0] != "admin" {
+		t.Errorf("unexpected roles %v", roles)
+	}
+	if aud := parsed.GetAudience(); len(aud) != 1 || aud[0] != "api" {
+		t.Errorf("expected single string audience to be a one element slice, got %v", aud)
+	}
+	if quota, ok := parsed.Int64("quota"); !ok || quota != 9007199254740993 {
+		t.Errorf("expected integer without precision loss, got %d", quota)
+	}
+	if exp, ok := parsed.Time("exp"); !ok || exp.Unix() != now.Add(time.Hour).Unix() {
+		t.Errorf("unexpected exp %v", exp)
+	}
+	if parsed.GetIssuedAt() == nil || parsed.GetIssuedAt().Unix() != now.Unix() {
+		t.Errorf("unexpected iat %v", parsed.GetIssuedAt())
+	}
+
+	if _, ok := parsed.Int64("tenant"); ok {
+		t.Error("expected string claim not to be an Int64")
+	}
+	if _, ok := parsed.String("missing"); ok {
+		t.Error("expected missing claim not to be found")
+	}
+}
+
+func TestMapClaims_Validation(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	claims := MapClaims{
+		"iss": "https://issuer.example.com",
+		"sub": "user-1",
+		"aud": []string{"other", "api"},
+		"exp": time.Now().Add(-time.Minute).Unix(),
+		"nbf": time.Now().Add(-time.Hour).Unix(),
+		"iat": time.Now().Add(-time.Hour).Unix(),
+	}
+
+	signed, _ := NewToken(&claims).SignedString(&privateKey)
+
+	_, err := VerifyToken[MapClaims](signed, &publicKey, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+
+	expected := validTestExpectedClaims()
+	expected.ExpiresAtLeeway = time.Hour
+	expected.Validators = []Validator{ClaimOneOf("sub", "user-1")}
+	if _, err := VerifyToken[MapClaims](signed, &publicKey, expected); err != nil {
+		t.Errorf("expected array audience and validators to work, got %v", err)
+	}
+}
+
+func TestMapClaims_UnsecureDecodeToken(t *testing.T) {
+	tests := map[string]struct {
+		claims map[string]any
+		err    error
+	}{
+		"valid":            {claims: map[string]any{"sub": "user-1", "exp": 1700000000.5, "aud": []string{"a", "b"}}},
+		"string exp":       {claims: map[string]any{"exp": "tomorrow"}, err: ErrTokenMalformed},
+		"numeric issuer":   {claims: map[string]any{"iss": 42}, err: ErrTokenMalformed},
+		"numeric audience": {claims: map[string]any{"aud": []any{"a", 1}}, err: ErrTokenMalformed},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tokenString := signTestToken(t, map[string]any{"alg": "EdDSA"}, tt.claims, func(string) ([]byte, error) { return []byte("sig"), nil })
+
+			token, err := UnsecureDecodeToken[MapClaims](tokenString)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if token.Claims.GetSubject() != "user-1" || token.Claims.GetExpirationTime().Unix() != 1700000000 || len(token.Claims.GetAudience()) != 2 {
+				t.Errorf("unexpected claims %v", *token.Claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrMissingBearerToken  = errors.New("jwt: request carries no bearer token")
+	ErrMultipleBearerToken = errors.New("jwt: request carries more than one bearer token")
+	ErrInsufficientScope   = errors.New("jwt: token lacks a required scope")
+	ErrMiddlewareNoKey     = errors.New("jwt: middleware requires a Key, KeySet or JWKS")
+)
+
+// MiddlewareConfig configures the bearer token authentication of [Middleware]. Exactly one of
+// Key, KeySet or JWKS is used to verify tokens, in this order of precedence.
+type MiddlewareConfig struct {
+	// Key verifies tokens as in [VerifyToken].
+	Key *PublicKey
+	// KeySet verifies tokens as in [VerifyTokenWithKeySet].
+	KeySet *KeySet
+	// JWKS and Algorithms verify tokens as in [VerifyTokenWithJWKS].
+	JWKS       JWKSource
+	Algorithms []string
+	// Expected are the claims every token is validated against.
+	Expected *ExpectedClaims
+
+	// Realm is announced in the `WWW-Authenticate` header if set.
+	Realm string
+	// Cookie is the name of a cookie the token is read from if the request has no
+	// `Authorization` header. Empty disables cookies.
+	Cookie string
+	// QueryParameter is the name of the URI query parameter the token is read from, usually
+	// `access_token`, see https://datatracker.ietf.org/doc/html/rfc6750#section-2.3. Empty
+	// disables query parameters.
+	QueryParameter string
+	// Scopes which every token has to grant, read from the space separated `scope` claim or
+	// the `scp` array claim.
+	Scopes []string
+}
+
+// tokenContextKey is the key the verified token is stored under in the request context.
+type tokenContextKey struct{}
+
+// Middleware returns a net/http middleware which authenticates requests with a bearer token,
+// see https://datatracker.ietf.org/doc/html/rfc6750. The verified token is stored in the request
+// context and can be retrieved with [TokenFromContext]. Requests without a valid token are
+// rejected with a `WWW-Authenticate` challenge and are never passed to the next handler. If the
+// token could not be checked because the JWKS or the revocation state is unavailable, the
+// request is rejected with 503 Service Unavailable instead.
+//
+// Middleware panics with ErrMiddlewareNoKey if config has no key material, as such a
+// middleware could not accept any request. The misconfiguration therefore surfaces when the
+// handlers are set up and not with the first request.
+func Middleware[T Claims](config MiddlewareConfig) func(http.Handler) http.Handler {
+	verify, err := middlewareVerifier[T](config)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, err := extractBearerToken(r, config)
+			if errors.Is(err, ErrMissingBearerToken) {
+				writeBearerChallenge(w, config.Realm, http.StatusUnauthorized, "", "", nil)
+				return
+			}
+			if err != nil {
+				writeBearerChallenge(w, config.Realm, http.StatusBadRequest, "invalid_request", "request carries more than one bearer token", nil)
+				return
+			}
+
+			token, err := verify(tokenString)
+			if errors.Is(err, ErrJWKSUnavailable) || errors.Is(err, ErrRevocationUnavailable) {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if err != nil {
+				writeBearerChallenge(w, config.Realm, http.StatusUnauthorized, "invalid_token", invalidTokenDescription(err), nil)
+				return
+			}
+
+			if len(config.Scopes) > 0 && !hasScopes(*token.Claims, config.Scopes) {
+				writeBearerChallenge(w, config.Realm, http.StatusForbidden, "insufficient_scope", "token lacks a required scope", config.Scopes)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenContextKey{}, token)))
+		})
+	}
+}
+
+// TokenFromContext returns the token stored by [Middleware] in the context. It returns false if
+// the context carries no token or a token with claims of another type than T.
+func TokenFromContext[T Claims](ctx context.Context) (*Token[T], bool) {
+	token, ok := ctx.Value(tokenContextKey{}).(*Token[T])
+	return token, ok
+}
+
+// middlewareVerifier returns the verify function for the key material of config. It returns
+// ErrMiddlewareNoKey if no key material is configured.
+func middlewareVerifier[T Claims](config MiddlewareConfig) (func(tokenString string) (*Token[T], error), error) {
+	switch {
+	case config.Key != nil:
+		return func(tokenString string) (*Token[T], error) {
+			return VerifyToken[T](tokenString, config.Key, config.Expected)
+		}, nil
+	case config.KeySet != nil:
+		return func(tokenString string) (*Token[T], error) {
+			return VerifyTokenWithKeySet[T](tokenString, config.KeySet, config.Expected)
+		}, nil
+	case config.JWKS != nil:
+		return func(tokenString string) (*Token[T], error) {
+			return VerifyTokenWithJWKS[T](tokenString, config.JWKS, config.Algorithms, config.Expected)
+		}, nil
+	}
+
+	return nil, ErrMiddlewareNoKey
+}
+
+// invalidTokenDescription maps a verification error to a fixed `error_description`, so the
+// challenge does not leak internal details like key ids or the claim values expected.
+func invalidTokenDescription(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, ErrTokenRevoked):
+		return "token is revoked"
+	case errors.As(err, new(*ValidationError)):
+		return "token claims are invalid"
+	}
+
+	return "token is invalid"
+}
+
+// extractBearerToken reads the token from the `Authorization` header, the configured cookie or
+// the configured query parameter. Clients must not use more than one method at once, see
+// https://datatracker.ietf.org/doc/html/rfc6750#section-2. The cookie is only considered if no
+// header is present, since browsers attach it to every request.
+func extractBearerToken(r *http.Request, config MiddlewareConfig) (string, error) {
+	var tokens []string
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+			tokens = append(tokens, token)
+		}
+	} else if config.Cookie != "" {
+		if cookie, err := r.Cookie(config.Cookie); err == nil && cookie.Value != "" {
+			tokens = append(tokens, cookie.Value)
+		}
+	}
+
+	if config.QueryParameter != "" && r.URL.Query().Has(config.QueryParameter) {
+		tokens = append(tokens, r.URL.Query().Get(config.QueryParameter))
+	}
+
+	switch {
+	case len(tokens) > 1:
+		return "", ErrMultipleBearerToken
+	case len(tokens) == 0 || tokens[0] == "":
+		return "", ErrMissingBearerToken
+	}
+
+	return tokens[0], nil
+}
+
+// hasScopes reports whether the claims grant all required scopes.
+func hasScopes(claims Claims, required []string) bool {
+	ctx := &ValidationContext{Claims: claims}
+
+	var granted []string
+	if scope, ok := ClaimValue[string](ctx, "scope"); ok {
+		granted = strings.Fields(scope)
+	} else if scp, ok := ClaimValue[[]string](ctx, "scp"); ok {
+		granted = scp
+	}
+
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeBearerChallenge writes an error response with the `WWW-Authenticate` header described
+// in https://datatracker.ietf.org/doc/html/rfc6750#section-3.
+func writeBearerChallenge(w http.ResponseWriter, realm string, status int, code string, description string, scopes []string) {
+	var params []string
+	if realm != "" {
+		params = append(params, `realm="`+quoteChallengeValue(realm)+`"`)
+	}
+	if code != "" {
+		params = append(params, `error="`+code+`"`)
+	}
+	if description != "" {
+		params = append(params, `error_description="`+quoteChallengeValue(description)+`"`)
+	}
+	if len(scopes) > 0 {
+		params = append(params, `scope="`+quoteChallengeValue(strings.Join(scopes, " "))+`"`)
+	}
+
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// quoteChallengeValue removes the characters which may not appear within a quoted
+// `WWW-Authenticate` parameter value.
+func quoteChallengeValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type middlewareTestClaims struct {
+	Scope string `json:"scope,omitempty"`
+	RegisteredClaims
+}
+
+func newMiddlewareTestServer(t *testing.T, config MiddlewareConfig) (http.Handler, *PrivateKey) {
+	t.Helper()
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pub, priv := PublicKey(publicKey), PrivateKey(privateKey)
+
+	config.Key = &pub
+	config.Expected = validTestExpectedClaims()
+
+	handler := Middleware[middlewareTestClaims](config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := TokenFromContext[middlewareTestClaims](r.Context())
+		if !ok {
+			t.Error("expected token in request context")
+			return
+		}
+		w.Write([]byte(token.Claims.Subject))
+	}))
+
+	return handler, &priv
+}
+
+func signMiddlewareTestToken(t *testing.T, key *PrivateKey, scope string) string {
+	t.Helper()
+
+	signed, err := NewToken(&middlewareTestClaims{Scope: scope, RegisteredClaims: *validTestClaims()}).SignedString(key)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	return signed
+}
+
+func TestMiddleware_AuthorizationHeader(t *testing.T) {
+	handler, key := newMiddlewareTestServer(t, MiddlewareConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "bearer "+signMiddlewareTestToken(t, key, ""))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "user-1" {
+		t.Errorf("expected 200 with subject, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMiddleware_CookieAndQuery(t *testing.T) {
+	handler, key := newMiddlewareTestServer(t, MiddlewareConfig{Cookie: "session", QueryParameter: "access_token"})
+	token := signMiddlewareTestToken(t, key, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected cookie token to be accepted, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?access_token="+token, nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected query token to be accepted, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?access_token="+token, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_request"`) {
+		t.Errorf("expected invalid_request for two tokens, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestMiddleware_Challenges(t *testing.T) {
+	handler, key := newMiddlewareTestServer(t, MiddlewareConfig{Realm: "api", Scopes: []string{"read", "write"}})
+
+	otherKey := newTestSigningKey(t, "key-2", KeyStateActive)
+
+	tests := map[string]struct {
+		authorization string
+		status        int
+		challenge     string
+	}{
+		"missing token": {
+			status:    http.StatusUnauthorized,
+			challenge: `Bearer realm="api"`,
+		},
+		"other scheme": {
+			authorization: "Basic dXNlcjpwYXNz",
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="api"`,
+		},
+		"invalid token": {
+			authorization: "Bearer " + signMiddlewareTestToken(t, otherKey.PrivateKey, "read write"),
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="api", error="invalid_token", error_description="token is invalid"`,
+		},
+		"insufficient scope": {
+			authorization: "Bearer " + signMiddlewareTestToken(t, key, "read"),
+			status:        http.StatusForbidden,
+			challenge:     `Bearer realm="api", error="insufficient_scope", error_description="token lacks a required scope", scope="read write"`,
+		},
+		"sufficient scope": {
+			authorization: "Bearer " + signMiddlewareTestToken(t, key, "write admin read"),
+			status:        http.StatusOK,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("expected challenge %q, got %q", tt.challenge, got)
+			}
+		})
+	}
+}
+
+func TestTokenFromContext_WrongType(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tokenContextKey{}, &Token[middlewareTestClaims]{})
+
+	if _, ok := TokenFromContext[middlewareTestClaims](ctx); !ok {
+		t.Error("expected token with matching claims type")
+	}
+	if _, ok := TokenFromContext[RegisteredClaims](ctx); ok {
+		t.Error("expected no token for other claims type")
+	}
+	if _, ok := TokenFromContext[RegisteredClaims](context.Background()); ok {
+		t.Error("expected no token in empty context")
+	}
+}
+
+func TestMiddleware_RequiresKey(t *testing.T) {
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrMiddlewareNoKey) {
+			t.Errorf("expected panic with ErrMiddlewareNoKey, got %v", err)
+		}
+	}()
+
+	Middleware[RegisteredClaims](MiddlewareConfig{})
+}
+
+func TestMiddleware_ErrorDescriptions(t *testing.T) {
+	handler, key := newMiddlewareTestServer(t, MiddlewareConfig{})
+
+	expired := validTestClaims()
+	expired.ExpiresAt = &NumericDate{Time: time.Now().Add(-time.Hour)}
+	expiredToken, _ := NewToken(&middlewareTestClaims{RegisteredClaims: *expired}).SignedString(key)
+
+	otherIssuer := validTestClaims()
+	otherIssuer.Issuer = "https://other.example.com"
+	otherIssuerToken, _ := NewToken(&middlewareTestClaims{RegisteredClaims: *otherIssuer}).SignedString(key)
+
+	tests := map[string]struct {
+		token       string
+		description string
+	}{
+		"expired":      {token: expiredToken, description: "token is expired"},
+		"other issuer": {token: otherIssuerToken, description: "token claims are invalid"},
+		"malformed":    {token: "not-a-token", description: "token is invalid"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			want := `Bearer error="invalid_token", error_description="` + tt.description + `"`
+			if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != want {
+				t.Errorf("expected 401 with %q, got %d %q", want, rec.Code, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_JWKSUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	handler := Middleware[RegisteredClaims](MiddlewareConfig{JWKS: NewJWKSCache(server.URL), Algorithms: []string{"RS256"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected request to be rejected")
+	}))
+
+	rsaKey, _ := newRSATestJWKS(t, "rsa-1")
+	token := signTestToken(t, map[string]any{"alg": "RS256", "kid": "rsa-1"}, validTestClaims(), func(signingString string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(signingString, rsaKey)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("expected 503 without challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
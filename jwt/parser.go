@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"encoding/json"
+	"slices"
 	"strings"
 )
 
@@ -19,12 +20,93 @@ func VerifyToken[T Claims](tokenString string, key *PublicKey, expected *Expecte
 		return nil, ErrTokenInvalidAlgorithm
 	}
 
-	tokenPayload := token.RawParts[0] + "." + token.RawParts[1]
+	tokenPayload := token.signingString()
 	if err := VerifyEd25519(tokenPayload, token.Signature, key); err != nil {
 		return nil, err
 	}
 
-	if err := validateClaims(*token.Claims, expected); err != nil {
+	if err := validateTokenClaims(token.Header, *token.Claims, expected); err != nil {
+		return nil, err
+	}
+
+	token.Valid = true
+
+	return token, nil
+}
+
+// VerifyTokenWithKeySet verifies a JWT token string signed by one of the keys of the key set.
+// The `kid` header selects the verification key, which has to be active or retiring and within
+// its validity window. Apart from the key lookup it behaves exactly like [VerifyToken].
+func VerifyTokenWithKeySet[T Claims](tokenString string, ks *KeySet, expected *ExpectedClaims) (*Token[T], error) {
+	token, err := UnsecureDecodeToken[T](tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Header["alg"] != "EdDSA" {
+		return nil, ErrTokenInvalidAlgorithm
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, ErrTokenMissingKid
+	}
+
+	key, err := ks.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPayload := token.signingString()
+	if err := VerifyEd25519(tokenPayload, token.Signature, key); err != nil {
+		return nil, err
+	}
+
+	if err := validateTokenClaims(token.Header, *token.Claims, expected); err != nil {
+		return nil, err
+	}
+
+	token.Valid = true
+
+	return token, nil
+}
+
+// VerifyTokenWithJWKS verifies a JWT token string signed by a third party issuer whose public
+// keys are published as a JWKS. The `alg` header has to be part of the algorithms allowlist
+// (only RS256 if the list is empty) and the `kid` header selects the verification key from jwks.
+// After the signature is verified, the claims are validated the same way as in [VerifyToken].
+// If the token is valid, it returns the parsed Token with its Valid field set to true.
+func VerifyTokenWithJWKS[T Claims](tokenString string, jwks JWKSource, algorithms []string, expected *ExpectedClaims) (*Token[T], error) {
+	token, err := UnsecureDecodeToken[T](tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(algorithms) == 0 {
+		algorithms = []string{SigningMethodRS256.Name}
+	}
+
+	alg, ok := token.Header["alg"].(string)
+	if !ok || !slices.Contains(algorithms, alg) {
+		return nil, ErrTokenInvalidAlgorithm
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, ErrTokenMissingKid
+	}
+
+	jwk, err := jwks.FindKeyByKid(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPayload := token.signingString()
+	if err := jwk.verifySignature(alg, tokenPayload, token.Signature); err != nil {
+		return nil, err
+	}
+
+	if err := validateTokenClaims(token.Header, *token.Claims, expected); err != nil {
 		return nil, err
 	}
 
@@ -58,7 +140,8 @@ func UnsecureDecodeToken[T Claims](tokenString string) (*Token[T], error) {
 	if err != nil {
 		return nil, ErrTokenMalformed
 	}
-	if err := json.Unmarshal(claimBytes, &token.Claims); err != nil {
+	// A `null` payload decodes without error but leaves Claims nil.
+	if err := json.Unmarshal(claimBytes, &token.Claims); err != nil || token.Claims == nil {
 		return token, ErrTokenMalformed
 	}
 
@@ -1,8 +1,16 @@
 package jwt
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"errors"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 )
 
 // ====== Benchmarks ======
@@ -150,6 +158,13 @@ func TestUnsecureDecodeToken_InvalidJSONClaims(t *testing.T) {
 	}
 }
 
+func TestUnsecureDecodeToken_NullClaims(t *testing.T) {
+	tokenString := _EncodeSegment([]byte("{}")) + "." + _EncodeSegment([]byte("null")) + "." + _EncodeSegment([]byte("sig"))
+	if _, err := UnsecureDecodeToken[RegisteredClaims](tokenString); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected ErrTokenMalformed, got %v", err)
+	}
+}
+
 func TestUnsecureDecodeToken_InvalidBase64Signature(t *testing.T) {
 	tokenString := _EncodeSegment([]byte("{}")) + "." + _EncodeSegment([]byte("{}")) + ".!!invalid!!"
 	token, err := UnsecureDecodeToken[RegisteredClaims](tokenString)
@@ -203,3 +218,218 @@ func TestVerifyToken_SignatureVerificationFail(t *testing.T) {
 		t.Errorf("expected ErrEd25519Verification, got %v", err)
 	}
 }
+
+/* VerifyTokenWithJWKS */
+
+// signTestToken creates a compact JWS with the given header and claims using sign.
+func signTestToken(t testing.TB, header map[string]any, claims any, sign func(signingString string) ([]byte, error)) string {
+	t.Helper()
+
+	headerBytes, _ := json.Marshal(header)
+	claimBytes, _ := json.Marshal(claims)
+	signingString := _EncodeSegment(headerBytes) + "." + _EncodeSegment(claimBytes)
+
+	sig, err := sign(signingString)
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	return signingString + "." + _EncodeSegment(sig)
+}
+
+// validTestClaims returns registered claims which pass validation against validTestExpectedClaims.
+func validTestClaims() *RegisteredClaims {
+	return &RegisteredClaims{
+		Issuer:    "https://issuer.example.com",
+		Subject:   "user-1",
+		Audience:  Audience{"api"},
+		ExpiresAt: &NumericDate{time.Now().Add(time.Hour)},
+		NotBefore: &NumericDate{time.Now().Add(-time.Minute)},
+		IssuedAt:  &NumericDate{time.Now().Add(-time.Minute)},
+	}
+}
+
+func validTestExpectedClaims() *ExpectedClaims {
+	return &ExpectedClaims{Issuer: "https://issuer.example.com", Audience: []string{"api"}}
+}
+
+func newRSATestJWKS(t testing.TB, kid string) (*rsa.PrivateKey, *JWKS) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	return key, &JWKS{Keys: []JWK{{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   _EncodeSegment(key.N.Bytes()),
+		E:   _EncodeSegment(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+}
+
+func TestVerifyTokenWithJWKS_ValidToken(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+
+	for _, method := range []*SigningMethodRSA{SigningMethodRS256, SigningMethodRS384, SigningMethodRS512} {
+		tokenString := signTestToken(t, map[string]any{"alg": method.Alg(), "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+			return method.SignRSA(s, key)
+		})
+
+		token, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, []string{"RS256", "RS384", "RS512"}, validTestExpectedClaims())
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", method.Alg(), err)
+		}
+		if !token.Valid {
+			t.Errorf("%s: expected token to be valid", method.Alg())
+		}
+	}
+}
+
+func TestVerifyTokenWithJWKS_AlgorithmNotAllowed(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS384", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS384.SignRSA(s, key)
+	})
+
+	_, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenInvalidAlgorithm) {
+		t.Errorf("expected ErrTokenInvalidAlgorithm, got %v", err)
+	}
+
+	tokenString = _EncodeSegment([]byte(`{"alg":"none","kid":"key-1"}`)) + "." + _EncodeSegment([]byte(`{"sub":"123"}`)) + "."
+	_, err = VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, []string{"none"}, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenInvalidAlgorithm) {
+		t.Errorf("expected ErrTokenInvalidAlgorithm for alg 'none', got %v", err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_KeyAlgorithmMismatch(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+	jwks.Keys[0].Alg = "RS512"
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, key)
+	})
+
+	_, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, []string{"RS256", "RS512"}, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenInvalidAlgorithm) {
+		t.Errorf("expected ErrTokenInvalidAlgorithm, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_MissingAndUnknownKid(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+	sign := func(s string) ([]byte, error) { return SigningMethodRS256.SignRSA(s, key) }
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS256"}, validTestClaims(), sign)
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims()); !errors.Is(err, ErrTokenMissingKid) {
+		t.Errorf("expected ErrTokenMissingKid, got %v", err)
+	}
+
+	tokenString = signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-2"}, validTestClaims(), sign)
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims()); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_SignatureVerificationFail(t *testing.T) {
+	_, jwks := newRSATestJWKS(t, "key-1")
+	otherKey, _ := newRSATestJWKS(t, "key-1")
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, otherKey)
+	})
+
+	_, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims())
+	if !errors.Is(err, ErrRSAVerification) {
+		t.Errorf("expected ErrRSAVerification, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_InvalidClaims(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+
+	claims := validTestClaims()
+	claims.ExpiresAt = &NumericDate{time.Now().Add(-time.Minute)}
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims, func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, key)
+	})
+
+	_, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims())
+	if err == nil || !strings.Contains(err.Error(), ErrTokenExpired.Error()) {
+		t.Errorf("expected error containing %q, got %v", ErrTokenExpired, err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_NullClaims(t *testing.T) {
+	key, jwks := newRSATestJWKS(t, "key-1")
+
+	tokenString := signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, nil, func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, key)
+	})
+
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims()); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected ErrTokenMalformed, got %v", err)
+	}
+}
+
+func TestVerifyTokenWithJWKS_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	point, _ := key.PublicKey.Bytes()
+	jwks := &JWKS{Keys: []JWK{{
+		Kty: "EC",
+		Crv: "P-256",
+		Kid: "ec-1",
+		X:   _EncodeSegment(point[1:33]),
+		Y:   _EncodeSegment(point[33:]),
+	}}}
+
+	tokenString := signTestToken(t, map[string]any{"alg": "ES256", "kid": "ec-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodES256.SignECDSA(s, key)
+	})
+
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, []string{"ES256"}, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, nil, validTestExpectedClaims()); !errors.Is(err, ErrTokenInvalidAlgorithm) {
+		t.Errorf("expected ErrTokenInvalidAlgorithm when ES256 is not allowed, got %v", err)
+	}
+
+	jwks.Keys[0].Kty = "RSA"
+	if _, err := VerifyTokenWithJWKS[RegisteredClaims](tokenString, jwks, []string{"ES256"}, validTestExpectedClaims()); !errors.Is(err, ErrInvalidKeyType) {
+		t.Errorf("expected ErrInvalidKeyType, got %v", err)
+	}
+}
+
+func TestVerifyToken_ExpiredTokenIsInspectable(t *testing.T) {
+	privateKey, _ := ParseEd25519PrivateKey("MC4CAQAwBQYDK2VwBCIEIJ7VP4bGde7HFmugf7wnZ+f09S4wXiHTPqCQB/HYLw+s")
+	publicKey, _ := ParseEd25519PublicKey("MCowBQYDK2VwAyEA7rD1JBNE9qhzXQBN3mltLsAQy34dwDljiSPzmYeqiiM=")
+
+	claims := validTestClaims()
+	claims.ExpiresAt = &NumericDate{time.Now().Add(-time.Minute)}
+
+	tokenString, err := NewToken(claims).SignedString(&privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	_, err = VerifyToken[RegisteredClaims](tokenString, &publicKey, validTestExpectedClaims())
+	if !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected errors.Is(err, ErrTokenExpired), got %v", err)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.HasClaim("exp") {
+		t.Errorf("expected *ValidationError for claim 'exp', got %v", err)
+	}
+}
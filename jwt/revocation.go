@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTokenRevoked          = errors.New("jwt: token is revoked")
+	ErrRevocationUnavailable = errors.New("jwt: revocation state of the token could not be determined")
+)
+
+// RevocationChecker is consulted during verification when set on [ExpectedClaims.Revocation],
+// after all other claims passed validation. Implementations can look up the `jti` claim in a
+// denylist or compare the `iat` claim against a per-subject revocation timestamp.
+type RevocationChecker interface {
+	// IsRevoked reports whether the token carrying claims was revoked. An error aborts the
+	// verification, it should only be returned if the revocation state could not be determined.
+	// The verification then fails with the error wrapped in ErrRevocationUnavailable.
+	IsRevoked(claims Claims) (bool, error)
+}
+
+// RevokedClaimChecker can be implemented by a RevocationChecker to report the claim a token
+// was revoked by, which is then named in the resulting [ClaimError]. Without it, revoked
+// tokens are reported on the `jti` claim.
+type RevokedClaimChecker interface {
+	// RevokedClaim returns the name of the claim the token carrying claims was revoked by,
+	// e.g. `jti` or `sub`, or an empty string if the token is not revoked.
+	RevokedClaim(claims Claims) (string, error)
+}
+
+// RevocationList is an in-memory RevocationChecker. Tokens can be revoked one by one by
+// their `jti` claim, or all tokens of a subject issued before a point in time can be revoked
+// at once (logout everywhere). Every entry carries an expiry after which it is pruned; it
+// should be set to the moment the revoked tokens would expire anyway.
+// A RevocationList is safe for concurrent use, and its zero value is an empty list.
+type RevocationList struct {
+	mu       sync.RWMutex
+	ids      map[string]time.Time
+	subjects map[string]subjectRevocation
+	now      func() time.Time
+}
+
+type subjectRevocation struct {
+	before time.Time
+	until  time.Time
+}
+
+// NewRevocationList creates a new, empty RevocationList.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{
+		ids:      make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+		now:      time.Now,
+	}
+}
+
+// RevokeID revokes the token with the given `jti` claim. The entry is kept until the given
+// time, usually the `exp` claim of the token.
+func (l *RevocationList) RevokeID(id string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	if l.ids == nil {
+		l.ids = make(map[string]time.Time)
+	}
+	l.ids[id] = until
+}
+
+// RevokeSubject revokes all tokens of the given subject which were issued before the given
+// time. The entry is kept until the given time, which should be at least before plus the
+// maximum lifetime of a token.
+func (l *RevocationList) RevokeSubject(subject string, before time.Time, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	revocation := subjectRevocation{before: before, until: until}
+	if existing, ok := l.subjects[subject]; ok {
+		if existing.before.After(revocation.before) {
+			revocation.before = existing.before
+		}
+		if existing.until.After(revocation.until) {
+			revocation.until = existing.until
+		}
+	}
+
+	if l.subjects == nil {
+		l.subjects = make(map[string]subjectRevocation)
+	}
+	l.subjects[subject] = revocation
+}
+
+// IsRevoked implements the RevocationChecker interface.
+func (l *RevocationList) IsRevoked(claims Claims) (bool, error) {
+	claim, err := l.RevokedClaim(claims)
+	return claim != "", err
+}
+
+// RevokedClaim implements the RevokedClaimChecker interface. It returns `jti` if the token
+// was revoked by its ID and `sub` if all tokens of its subject were revoked.
+func (l *RevocationList) RevokedClaim(claims Claims) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := l.clock()
+
+	if id := claims.GetID(); id != "" {
+		if until, ok := l.ids[id]; ok && !now.After(until) {
+			return "jti", nil
+		}
+	}
+
+	if revocation, ok := l.subjects[claims.GetSubject()]; ok && !now.After(revocation.until) {
+		iat := claims.GetIssuedAt()
+		if iat == nil || iat.Before(revocation.before) {
+			return "sub", nil
+		}
+	}
+
+	return "", nil
+}
+
+// Prune removes all expired entries from the list. Entries are also pruned whenever a new
+// revocation is added.
+func (l *RevocationList) Prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+}
+
+// prune removes all expired entries. The caller must hold the write lock.
+func (l *RevocationList) prune() {
+	now := l.clock()
+
+	for id, until := range l.ids {
+		if now.After(until) {
+			delete(l.ids, id)
+		}
+	}
+
+	for subject, revocation := range l.subjects {
+		if now.After(revocation.until) {
+			delete(l.subjects, subject)
+		}
+	}
+}
+
+// clock returns the current time, also for lists which were not created with
+// [NewRevocationList].
+func (l *RevocationList) clock() time.Time {
+	if l.now == nil {
+		return time.Now()
+	}
+
+	return l.now()
+}
@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingRevocationChecker struct{}
+
+func (failingRevocationChecker) IsRevoked(Claims) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func TestRevocationList_RevokeID(t *testing.T) {
+	list := NewRevocationList()
+
+	claims := validTestClaims()
+	claims.ID = "token-1"
+
+	if revoked, _ := list.IsRevoked(claims); revoked {
+		t.Error("expected token not to be revoked")
+	}
+
+	list.RevokeID("token-1", claims.ExpiresAt.Time)
+
+	if revoked, _ := list.IsRevoked(claims); !revoked {
+		t.Error("expected token to be revoked")
+	}
+
+	other := validTestClaims()
+	other.ID = "token-2"
+	if revoked, _ := list.IsRevoked(other); revoked {
+		t.Error("expected other token not to be revoked")
+	}
+}
+
+func TestRevocationList_RevokeSubject(t *testing.T) {
+	now := time.Now()
+	list := NewRevocationList()
+	list.now = func() time.Time { return now }
+
+	before := validTestClaims()
+	before.IssuedAt = &NumericDate{now.Add(-time.Minute)}
+
+	after := validTestClaims()
+	after.IssuedAt = &NumericDate{now.Add(time.Minute)}
+
+	list.RevokeSubject(before.Subject, now, now.Add(time.Hour))
+
+	if revoked, _ := list.IsRevoked(before); !revoked {
+		t.Error("expected token issued before the revocation to be revoked")
+	}
+	if revoked, _ := list.IsRevoked(after); revoked {
+		t.Error("expected token issued after the revocation not to be revoked")
+	}
+
+	// An earlier revocation must not shorten an existing one.
+	list.RevokeSubject(before.Subject, now.Add(-time.Hour), now)
+	if revoked, _ := list.IsRevoked(before); !revoked {
+		t.Error("expected revocation to be kept")
+	}
+}
+
+func TestRevocationList_ZeroValue(t *testing.T) {
+	var list RevocationList
+
+	claims := validTestClaims()
+	claims.ID = "token-1"
+
+	if claim, err := list.RevokedClaim(claims); claim != "" || err != nil {
+		t.Errorf("expected empty list not to revoke, got %q, %v", claim, err)
+	}
+
+	list.RevokeID("token-1", claims.ExpiresAt.Time)
+	list.RevokeSubject("user-2", time.Now(), time.Now().Add(time.Hour))
+
+	if claim, _ := list.RevokedClaim(claims); claim != "jti" {
+		t.Errorf("expected token to be revoked by jti, got %q", claim)
+	}
+	list.Prune()
+}
+
+func TestRevocationList_Prune(t *testing.T) {
+	now := time.Now()
+	list := NewRevocationList()
+	list.now = func() time.Time { return now }
+
+	list.RevokeID("token-1", now.Add(time.Minute))
+	list.RevokeSubject("user-1", now, now.Add(time.Minute))
+
+	now = now.Add(2 * time.Minute)
+	list.Prune()
+
+	if len(list.ids) != 0 || len(list.subjects) != 0 {
+		t.Errorf("expected expired entries to be pruned, got %d ids and %d subjects", len(list.ids), len(list.subjects))
+	}
+}
+
+func TestVerifyToken_Revoked(t *testing.T) {
+	privateKey, _ := ParseEd25519PrivateKey("MC4CAQAwBQYDK2VwBCIEIJ7VP4bGde7HFmugf7wnZ+f09S4wXiHTPqCQB/HYLw+s")
+	publicKey, _ := ParseEd25519PublicKey("MCowBQYDK2VwAyEA7rD1JBNE9qhzXQBN3mltLsAQy34dwDljiSPzmYeqiiM=")
+
+	claims := validTestClaims()
+	claims.ID = "token-1"
+	tokenString, _ := NewToken(claims).SignedString(&privateKey)
+
+	list := NewRevocationList()
+	expected := validTestExpectedClaims()
+	expected.Revocation = list
+
+	if _, err := VerifyToken[RegisteredClaims](tokenString, &publicKey, expected); err != nil {
+		t.Fatalf("expected no error before revocation, got %v", err)
+	}
+
+	list.RevokeID("token-1", claims.ExpiresAt.Time)
+
+	_, err := VerifyToken[RegisteredClaims](tokenString, &publicKey, expected)
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected ErrTokenRevoked, got %v", err)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.HasClaim("jti") {
+		t.Errorf("expected *ValidationError for claim 'jti', got %v", err)
+	}
+
+	expected.Revocation = failingRevocationChecker{}
+	if _, err := VerifyToken[RegisteredClaims](tokenString, &publicKey, expected); err == nil || errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected checker error to be returned, got %v", err)
+	}
+}
+
+func TestVerifyToken_RevokedSubject(t *testing.T) {
+	privateKey, _ := ParseEd25519PrivateKey("MC4CAQAwBQYDK2VwBCIEIJ7VP4bGde7HFmugf7wnZ+f09S4wXiHTPqCQB/HYLw+s")
+	publicKey, _ := ParseEd25519PublicKey("MCowBQYDK2VwAyEA7rD1JBNE9qhzXQBN3mltLsAQy34dwDljiSPzmYeqiiM=")
+
+	claims := validTestClaims()
+	tokenString, _ := NewToken(claims).SignedString(&privateKey)
+
+	list := NewRevocationList()
+	list.RevokeSubject(claims.Subject, time.Now().Add(time.Minute), time.Now().Add(time.Hour))
+
+	expected := validTestExpectedClaims()
+	expected.Revocation = list
+
+	_, err := VerifyToken[RegisteredClaims](tokenString, &publicKey, expected)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.HasClaim("sub") || validationErr.HasClaim("jti") {
+		t.Errorf("expected *ValidationError for claim 'sub', got %v", err)
+	}
+}
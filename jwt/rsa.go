@@ -28,6 +28,21 @@ func init() {
 	SigningMethodRS512 = &SigningMethodRSA{"RS512", crypto.SHA512}
 }
 
+// signingMethodRSA returns the RSA signing method registered for the given `alg` header
+// value, or nil if alg is not an RSA algorithm.
+func signingMethodRSA(alg string) *SigningMethodRSA {
+	switch alg {
+	case SigningMethodRS256.Name:
+		return SigningMethodRS256
+	case SigningMethodRS384.Name:
+		return SigningMethodRS384
+	case SigningMethodRS512.Name:
+		return SigningMethodRS512
+	}
+
+	return nil
+}
+
 func (m *SigningMethodRSA) Alg() string {
 	return m.Name
 }
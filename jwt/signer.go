@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+)
+
+// Signer creates Ed25519 signatures without requiring the private key to be held in process
+// memory, e.g. backed by an HSM or a remote KMS. A *PrivateKey is the in-process Signer.
+type Signer interface {
+	// Public returns the Ed25519 public key the signatures can be verified with.
+	Public() PublicKey
+	// Sign returns the Ed25519 signature of message.
+	Sign(message []byte) ([]byte, error)
+}
+
+// Sign implements the Signer interface.
+func (key *PrivateKey) Sign(message []byte) ([]byte, error) {
+	if key == nil || len(*key) != ed25519.PrivateKeySize {
+		return nil, ErrNotEdPrivateKey
+	}
+
+	return ed25519.Sign(ed25519.PrivateKey(*key), message), nil
+}
+
+// cryptoSigner adapts a crypto.Signer holding an Ed25519 key to the Signer interface.
+type cryptoSigner struct {
+	signer    crypto.Signer
+	publicKey PublicKey
+}
+
+// NewCryptoSigner wraps any crypto.Signer with an Ed25519 public key, such as the signers of
+// HSM (PKCS #11) or KMS client libraries, so it can sign tokens.
+func NewCryptoSigner(signer crypto.Signer) (Signer, error) {
+	publicKey, ok := signer.Public().(ed25519.PublicKey)
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrNotEdPublicKey
+	}
+
+	return &cryptoSigner{signer: signer, publicKey: PublicKey(publicKey)}, nil
+}
+
+func (s *cryptoSigner) Public() PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the message itself, Ed25519 signers must not be given a digest.
+func (s *cryptoSigner) Sign(message []byte) ([]byte, error) {
+	return s.signer.Sign(rand.Reader, message, crypto.Hash(0))
+}
+
+// FileSigner is a reference Signer which reads a PEM encoded Ed25519 private key from a file
+// for every signature instead of keeping it in memory. It is meant for tests and local
+// development of code written against external key custody.
+type FileSigner struct {
+	Path string
+
+	publicKey PublicKey
+}
+
+// NewFileSigner creates a FileSigner for the PKCS #8 PEM file at path, see
+// [EncodeEd25519PrivateKeyPEM]. The file is read once to determine the public key.
+func NewFileSigner(path string) (*FileSigner, error) {
+	key, err := readPrivateKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSigner{Path: path, publicKey: key.Public()}, nil
+}
+
+// Public implements the Signer interface.
+func (s *FileSigner) Public() PublicKey {
+	return s.publicKey
+}
+
+// Sign implements the Signer interface. The key file has to still hold the key the FileSigner
+// was created with.
+func (s *FileSigner) Sign(message []byte) ([]byte, error) {
+	key, err := readPrivateKeyFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !key.Public().Equal(s.publicKey) {
+		return nil, ErrInvalidKey
+	}
+
+	return key.Sign(message)
+}
+
+// readPrivateKeyFile reads and parses a PEM encoded Ed25519 private key file.
+func readPrivateKeyFile(path string) (PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEd25519PrivateKeyPEM(data)
+}
@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewCryptoSigner(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pub := PublicKey(publicKey)
+
+	signer, err := NewCryptoSigner(privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signed, err := NewToken(validTestClaims()).SignedString(signer)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	if _, err := VerifyToken[RegisteredClaims](signed, &pub, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected token signed by crypto.Signer to verify, got %v", err)
+	}
+
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := NewCryptoSigner(ecdsaKey); !errors.Is(err, ErrNotEdPublicKey) {
+		t.Errorf("expected ErrNotEdPublicKey for ECDSA signer, got %v", err)
+	}
+}
+
+func TestFileSigner(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	data, _ := EncodeEd25519PrivateKeyPEM(&privateKey)
+
+	path := filepath.Join(t.TempDir(), "signing-key.pem")
+	os.WriteFile(path, data, 0o600)
+
+	signer, err := NewFileSigner(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !signer.Public().Equal(publicKey) {
+		t.Fatal("expected public key of the key file")
+	}
+
+	signed, err := NewToken(validTestClaims()).SignedString(signer)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+	if _, err := VerifyToken[RegisteredClaims](signed, &publicKey, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected token signed by FileSigner to verify, got %v", err)
+	}
+
+	otherKey, _, _ := GenerateEd25519KeyPair()
+	data, _ = EncodeEd25519PrivateKeyPEM(&otherKey)
+	os.WriteFile(path, data, 0o600)
+
+	if _, err := NewToken(validTestClaims()).SignedString(signer); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey after the key file changed, got %v", err)
+	}
+}
+
+func TestKeySet_ExternalSigner(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewCryptoSigner(privateKey)
+
+	ks, err := NewKeySet(SigningKey{Kid: "kms-1", Signer: signer})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signed, err := NewToken(validTestClaims()).SignedStringWithKeySet(ks)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	if _, err := VerifyTokenWithKeySet[RegisteredClaims](signed, ks, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected token to verify, got %v", err)
+	}
+
+	jws, err := NewToken(validTestClaims()).SignedJSONWithKeySet(ks)
+	if err != nil {
+		t.Fatalf("unexpected error signing JSON: %v", err)
+	}
+	if _, err := VerifyJSONTokenWithKeySet[RegisteredClaims](jws, ks, validTestExpectedClaims()); err != nil {
+		t.Errorf("expected JSON token to verify, got %v", err)
+	}
+}
+
+func TestSignedString_NilSigner(t *testing.T) {
+	if _, err := NewToken(validTestClaims()).SignedString(nil); !errors.Is(err, ErrNotEdPrivateKey) {
+		t.Errorf("expected ErrNotEdPrivateKey, got %v", err)
+	}
+
+	var key *PrivateKey
+	if _, err := NewToken(validTestClaims()).SignedString(key); !errors.Is(err, ErrNotEdPrivateKey) {
+		t.Errorf("expected ErrNotEdPrivateKey for nil key, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrThumbprintUnsupportedKey  = errors.New("jwt: thumbprints require an RSA, EC or OKP key with all public members")
+	ErrThumbprintUnsupportedHash = errors.New("jwt: thumbprint hash function is not supported")
+)
+
+// thumbprintURIPrefix is the URN prefix of JWK thumbprint URIs, see
+// https://datatracker.ietf.org/doc/html/rfc9278#section-3.
+const thumbprintURIPrefix = "urn:ietf:params:oauth:jwk-thumbprint:"
+
+// thumbprintHashNames are the names of the hash functions in the IANA "Named Information Hash
+// Algorithm" registry which are used in thumbprint URIs.
+var thumbprintHashNames = map[crypto.Hash]string{
+	crypto.SHA256: "sha-256",
+	crypto.SHA384: "sha-384",
+	crypto.SHA512: "sha-512",
+}
+
+// Thumbprint computes the JWK thumbprint of the key with the given hash function, usually
+// crypto.SHA256, see https://datatracker.ietf.org/doc/html/rfc7638. Only the required public
+// members are hashed, so the thumbprint of a private JWK equals the one of its public JWK and
+// does not depend on `kid`, `use` or `alg`.
+func (jwk *JWK) Thumbprint(hash crypto.Hash) (string, error) {
+	if !hash.Available() {
+		return "", ErrThumbprintUnsupportedHash
+	}
+
+	var members [][2]string
+	switch jwk.Kty {
+	case "EC":
+		members = [][2]string{{"crv", jwk.Crv}, {"kty", jwk.Kty}, {"x", jwk.X}, {"y", jwk.Y}}
+	case "OKP":
+		members = [][2]string{{"crv", jwk.Crv}, {"kty", jwk.Kty}, {"x", jwk.X}}
+	case "RSA":
+		members = [][2]string{{"e", jwk.E}, {"kty", jwk.Kty}, {"n", jwk.N}}
+	default:
+		return "", ErrThumbprintUnsupportedKey
+	}
+
+	// The members are already in lexicographic order, the JSON encoding must not contain any
+	// whitespace, see https://datatracker.ietf.org/doc/html/rfc7638#section-3.2.
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, member := range members {
+		if member[1] == "" {
+			return "", ErrThumbprintUnsupportedKey
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		name, _ := json.Marshal(member[0])
+		value, _ := json.Marshal(member[1])
+		sb.Write(name)
+		sb.WriteByte(':')
+		sb.Write(value)
+	}
+	sb.WriteByte('}')
+
+	h := hash.New()
+	h.Write([]byte(sb.String()))
+
+	return _EncodeSegment(h.Sum(nil)), nil
+}
+
+// ThumbprintURI returns the JWK thumbprint as URI, e.g.
+// `urn:ietf:params:oauth:jwk-thumbprint:sha-256:NzbLsXh8...`, which names the hash function
+// along with the thumbprint, see https://datatracker.ietf.org/doc/html/rfc9278. Only SHA-256,
+// SHA-384 and SHA-512 are supported.
+func (jwk *JWK) ThumbprintURI(hash crypto.Hash) (string, error) {
+	name, ok := thumbprintHashNames[hash]
+	if !ok {
+		return "", ErrThumbprintUnsupportedHash
+	}
+
+	thumbprint, err := jwk.Thumbprint(hash)
+	if err != nil {
+		return "", err
+	}
+
+	return thumbprintURIPrefix + name + ":" + thumbprint, nil
+}
+
+// thumbprint returns the JWK SHA-256 thumbprint of the key, or an empty string if the key has
+// no thumbprint.
+func (jwk *JWK) thumbprint() string {
+	thumbprint, _ := jwk.Thumbprint(crypto.SHA256)
+	return thumbprint
+}
+
+// withDefaultKid sets the `kid` of the key to its JWK SHA-256 thumbprint if it has none, so
+// the same key always gets the same kid wherever it is exported.
+func (jwk *JWK) withDefaultKid() *JWK {
+	if jwk.Kid == "" {
+		jwk.Kid = jwk.thumbprint()
+	}
+
+	return jwk
+}
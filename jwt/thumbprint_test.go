@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestJWKThumbprint_TestVectors(t *testing.T) {
+	tests := map[string]struct {
+		jwk        JWK
+		thumbprint string
+	}{
+		// https://datatracker.ietf.org/doc/html/rfc7638#section-3.1
+		"RSA": {
+			jwk: JWK{
+				Kty: "RSA",
+				N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+				E:   "AQAB",
+				Alg: "RS256",
+				Kid: "2011-04-29",
+			},
+			thumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		// https://datatracker.ietf.org/doc/html/rfc8037#appendix-A.3
+		"OKP": {
+			jwk:        JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			thumbprint: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tt.jwk.Thumbprint(crypto.SHA256)
+			if err != nil || got != tt.thumbprint {
+				t.Errorf("expected thumbprint %s, got %s (%v)", tt.thumbprint, got, err)
+			}
+		})
+	}
+}
+
+func TestJWKThumbprintURI(t *testing.T) {
+	jwk := JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+
+	uri, err := jwk.ThumbprintURI(crypto.SHA256)
+	if err != nil || uri != "urn:ietf:params:oauth:jwk-thumbprint:sha-256:kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("unexpected thumbprint URI %s (%v)", uri, err)
+	}
+
+	uri, err = jwk.ThumbprintURI(crypto.SHA512)
+	if err != nil || len(uri) != len("urn:ietf:params:oauth:jwk-thumbprint:sha-512:")+86 {
+		t.Errorf("unexpected SHA-512 thumbprint URI %s (%v)", uri, err)
+	}
+
+	if _, err := jwk.ThumbprintURI(crypto.SHA1); !errors.Is(err, ErrThumbprintUnsupportedHash) {
+		t.Errorf("expected ErrThumbprintUnsupportedHash, got %v", err)
+	}
+}
+
+func TestJWKThumbprint_Errors(t *testing.T) {
+	tests := map[string]JWK{
+		"symmetric key":  {Kty: "oct"},
+		"missing member": {Kty: "EC", Crv: "P-256", X: "x"},
+		"no kty":         {},
+	}
+
+	for name, jwk := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jwk.Thumbprint(crypto.SHA256); !errors.Is(err, ErrThumbprintUnsupportedKey) {
+				t.Errorf("expected ErrThumbprintUnsupportedKey, got %v", err)
+			}
+		})
+	}
+
+	jwk := JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	if _, err := jwk.Thumbprint(crypto.Hash(0)); !errors.Is(err, ErrThumbprintUnsupportedHash) {
+		t.Errorf("expected ErrThumbprintUnsupportedHash, got %v", err)
+	}
+}
+
+func TestJWKThumbprint_DefaultKid(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	public, _ := NewEd25519JWK(&publicKey, "")
+	private, _ := NewEd25519PrivateJWK(&privateKey, "")
+
+	thumbprint, _ := public.Thumbprint(crypto.SHA256)
+	if public.Kid != thumbprint || private.Kid != thumbprint {
+		t.Errorf("expected kid %s, got %s and %s", thumbprint, public.Kid, private.Kid)
+	}
+
+	named, _ := NewEd25519JWK(&publicKey, "key-1")
+	if named.Kid != "key-1" {
+		t.Errorf("expected explicit kid to be kept, got %s", named.Kid)
+	}
+	if tp, _ := named.Thumbprint(crypto.SHA256); tp != thumbprint {
+		t.Error("expected thumbprint to not depend on the kid")
+	}
+
+	rsaKey, _ := GenerateRSAKey(2048)
+	rsaJWK, _ := NewRSAPrivateJWK(rsaKey, "", "RS256")
+	if tp, _ := rsaJWK.Public().Thumbprint(crypto.SHA256); rsaJWK.Kid == "" || rsaJWK.Kid != tp {
+		t.Errorf("expected RSA kid %s, got %s", tp, rsaJWK.Kid)
+	}
+}
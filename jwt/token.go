@@ -26,8 +26,37 @@ func NewToken[T Claims](claims *T) *Token[T] {
 // using the SigningMethod specified in the token. Please refer to
 // https://golang-jwt.github.io/jwt/usage/signing_methods/#signing-methods-and-key-types
 // for an overview of the different signing methods and their respective key
-// types.
-func (t *Token[T]) SignedString(key *PrivateKey) (string, error) {
+// types. The signer is usually a *PrivateKey, but can be any Signer keeping the private
+// key outside of the process, see [NewCryptoSigner].
+func (t *Token[T]) SignedString(signer Signer) (string, error) {
+	if signer == nil {
+		return "", ErrNotEdPrivateKey
+	}
+
+	return t.signedString(func(signingString string) ([]byte, error) {
+		return signer.Sign([]byte(signingString))
+	})
+}
+
+// SignedStringWithKeySet creates and returns a complete, signed JWT using the active
+// signing key of the key set. The kid of the key is stamped into the `kid` header, so the
+// token can be verified with [VerifyTokenWithKeySet] even after the key was rotated.
+func (t *Token[T]) SignedStringWithKeySet(ks *KeySet) (string, error) {
+	key, err := ks.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	if t.Header == nil {
+		t.Header = map[string]any{"typ": "JWT", "alg": "EdDSA"}
+	}
+	t.Header["kid"] = key.Kid
+
+	return t.SignedString(key.signer())
+}
+
+// signedString serializes the header and claims of the token and signs them with sign.
+func (t *Token[T]) signedString(sign func(signingString string) ([]byte, error)) (string, error) {
 	header, err := json.Marshal(t.Header)
 	if err != nil {
 		return "", err
@@ -40,10 +69,16 @@ func (t *Token[T]) SignedString(key *PrivateKey) (string, error) {
 
 	signingString := _EncodeSegment(header) + "." + _EncodeSegment(claims)
 
-	sig, err := SignEd25519(signingString, key)
+	sig, err := sign(signingString)
 	if err != nil {
 		return "", err
 	}
 
 	return signingString + "." + _EncodeSegment(sig), nil
 }
+
+// signingString returns the signing input of a parsed token, the header and claims segments
+// including the dot between them, as a substring of Raw instead of concatenating the parts.
+func (t *Token[T]) signingString() string {
+	return t.Raw[:len(t.RawParts[0])+1+len(t.RawParts[1])]
+}
@@ -0,0 +1,228 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func BenchmarkVerifyToken(b *testing.B) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	token, _ := NewToken(validTestClaims()).SignedString(&privateKey)
+	expected := validTestExpectedClaims()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := VerifyToken[RegisteredClaims](token, &publicKey, expected); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParser_Verify(b *testing.B) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	token, _ := NewToken(validTestClaims()).SignedString(&privateKey)
+	parser := &Parser[RegisteredClaims]{Key: &publicKey, Expected: validTestExpectedClaims()}
+
+	var claims RegisteredClaims
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := parser.Verify(token, &claims); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyTokenWithJWKS(b *testing.B) {
+	key, jwks := newRSATestJWKS(b, "key-1")
+	token := signTestToken(b, map[string]any{"alg": "RS256", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, key)
+	})
+	expected := validTestExpectedClaims()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := VerifyTokenWithJWKS[RegisteredClaims](token, jwks, nil, expected); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParser_VerifyWithJWKS(b *testing.B) {
+	key, jwks := newRSATestJWKS(b, "key-1")
+	token := signTestToken(b, map[string]any{"alg": "RS256", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, key)
+	})
+	parser := &Parser[RegisteredClaims]{JWKS: jwks, Expected: validTestExpectedClaims()}
+
+	var claims RegisteredClaims
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := parser.Verify(token, &claims); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParser_Verify(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	ks, _ := NewKeySet(SigningKey{Kid: "key-1", PrivateKey: &privateKey, State: KeyStateActive})
+	rsaKey, jwks := newRSATestJWKS(t, "key-1")
+
+	signed, _ := NewToken(validTestClaims()).SignedString(&privateKey)
+	signedWithKeySet, _ := NewToken(validTestClaims()).SignedStringWithKeySet(ks)
+	signedRSA := signTestToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, validTestClaims(), func(s string) ([]byte, error) {
+		return SigningMethodRS256.SignRSA(s, rsaKey)
+	})
+
+	tests := map[string]struct {
+		parser *Parser[RegisteredClaims]
+		token  string
+		kid    string
+	}{
+		"key":     {parser: &Parser[RegisteredClaims]{Key: &publicKey, Expected: validTestExpectedClaims()}, token: signed},
+		"key set": {parser: &Parser[RegisteredClaims]{KeySet: ks, Expected: validTestExpectedClaims()}, token: signedWithKeySet, kid: "key-1"},
+		"jwks":    {parser: &Parser[RegisteredClaims]{JWKS: jwks, Expected: validTestExpectedClaims()}, token: signedRSA, kid: "key-1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var claims RegisteredClaims
+			header, err := tt.parser.Verify(tt.token, &claims)
+			if err != nil {
+				t.Fatalf("expected token to verify, got %v", err)
+			}
+
+			if header.Kid != tt.kid || claims.Subject != "user-1" || claims.Audience[0] != "api" {
+				t.Errorf("unexpected header %+v or claims %+v", header, claims)
+			}
+		})
+	}
+}
+
+func TestParser_Rejects(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	parser := &Parser[RegisteredClaims]{Key: &publicKey, Expected: validTestExpectedClaims()}
+
+	sign := func(s string) ([]byte, error) { return privateKey.Sign([]byte(s)) }
+	valid := signTestToken(t, map[string]any{"alg": "EdDSA"}, validTestClaims(), sign)
+
+	expired := validTestClaims()
+	expired.ExpiresAt = &NumericDate{time.Now().Add(-time.Minute)}
+
+	tests := map[string]struct {
+		token string
+		err   error
+	}{
+		"two parts":     {token: "a.b", err: ErrTokenMalformed},
+		"four parts":    {token: valid + ".d", err: ErrTokenMalformed},
+		"bad base64":    {token: "!!." + valid[strings.IndexByte(valid, '.')+1:], err: ErrTokenMalformed},
+		"tampered":      {token: valid[:len(valid)-4] + "AAAA", err: ErrEd25519Verification},
+		"wrong alg":     {token: signTestToken(t, map[string]any{"alg": "RS256"}, validTestClaims(), sign), err: ErrTokenInvalidAlgorithm},
+		"missing alg":   {token: signTestToken(t, map[string]any{}, validTestClaims(), sign), err: ErrTokenInvalidAlgorithm},
+		"crit":          {token: signTestToken(t, map[string]any{"alg": "EdDSA", "crit": []string{"exp"}}, validTestClaims(), sign), err: ErrTokenMalformed},
+		"expired":       {token: signTestToken(t, map[string]any{"alg": "EdDSA"}, expired, sign), err: ErrTokenExpired},
+		"bad claims":    {token: signTestToken(t, map[string]any{"alg": "EdDSA"}, map[string]any{"exp": "soon"}, sign), err: ErrTokenMalformed},
+		"null claims":   {token: signTestToken(t, map[string]any{"alg": "EdDSA"}, nil, sign), err: ErrTokenMalformed},
+		"header number": {token: signTestToken(t, map[string]any{"alg": 1}, validTestClaims(), sign), err: ErrTokenMalformed},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var claims RegisteredClaims
+			if _, err := parser.Verify(tt.token, &claims); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParser_ReusesClaims(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	parser := &Parser[MapClaims]{Key: &publicKey, Expected: validTestExpectedClaims()}
+
+	first := MapClaims{"tenant": "acme"}
+	for k, v := range map[string]any{"iss": "https://issuer.example.com", "sub": "user-1", "aud": "api", "exp": time.Now().Add(time.Hour).Unix(), "nbf": time.Now().Unix(), "iat": time.Now().Unix()} {
+		first[k] = v
+	}
+	second := MapClaims{}
+	for k, v := range first {
+		if k != "tenant" {
+			second[k] = v
+		}
+	}
+
+	firstToken, _ := NewToken(&first).SignedString(&privateKey)
+	secondToken, _ := NewToken(&second).SignedString(&privateKey)
+
+	var claims MapClaims
+	if _, err := parser.Verify(firstToken, &claims); err != nil {
+		t.Fatalf("expected first token to verify, got %v", err)
+	}
+	if _, err := parser.Verify(secondToken, &claims); err != nil {
+		t.Fatalf("expected second token to verify, got %v", err)
+	}
+
+	if _, ok := claims.String("tenant"); ok {
+		t.Error("expected claims of the first token to be reset")
+	}
+}
+
+func TestParser_Concurrent(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+	parser := &Parser[RegisteredClaims]{Key: &publicKey, Expected: validTestExpectedClaims()}
+
+	tokens := make([]string, 8)
+	for i := range tokens {
+		claims := validTestClaims()
+		claims.ID = strings.Repeat("x", i*100)
+		tokens[i], _ = NewToken(claims).SignedString(&privateKey)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Go(func() {
+			for range 50 {
+				var claims RegisteredClaims
+				if _, err := parser.Verify(tokens[i%len(tokens)], &claims); err != nil || len(claims.ID) != i%len(tokens)*100 {
+					t.Errorf("expected token %d to verify, got %q, %v", i%len(tokens), claims.ID, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestParser_Validators(t *testing.T) {
+	privateKey, publicKey, _ := GenerateEd25519KeyPair()
+
+	expected := validTestExpectedClaims()
+	expected.Validators = []Validator{RequireType("at+jwt")}
+	parser := &Parser[RegisteredClaims]{Key: &publicKey, Expected: expected}
+
+	token, _ := NewToken(validTestClaims()).SignedString(&privateKey)
+
+	var claims RegisteredClaims
+	if _, err := parser.Verify(token, &claims); !errors.Is(err, ErrTokenInvalidType) {
+		t.Errorf("expected validators to see the header, got %v", err)
+	}
+}
+
+func TestParser_RequiresKey(t *testing.T) {
+	privateKey, _, _ := GenerateEd25519KeyPair()
+	token, _ := NewToken(validTestClaims()).SignedString(&privateKey)
+
+	var claims RegisteredClaims
+	if _, err := (&Parser[RegisteredClaims]{}).Verify(token, &claims); !errors.Is(err, ErrParserNoKey) {
+		t.Errorf("expected ErrParserNoKey, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ var (
 	ErrTokenNotValidYet    = errors.New("jwt: token is not valid yet")
 	ErrTokenExpired        = errors.New("jwt: token is expired")
 	ErrTokenIssuedInFuture = errors.New("jwt: token is issued in the future")
+	ErrTokenTooOld         = errors.New("jwt: token exceeds the maximum age")
 	ErrIssuerMismatch      = errors.New("jwt: issuer does not match expected issuer")
 	ErrSubjectMismatch     = errors.New("jwt: subject does not match expected subject")
 	ErrAudienceMismatch    = errors.New("jwt: audience does not match expected audience")
@@ -29,62 +31,202 @@ type ExpectedClaims struct {
 	Subject string `json:"sub,omitempty"`
 	// Audience is the expected audience of the JWT.
 	Audience []string `json:"aud,omitempty"`
+
+	// Now returns the time the time based claims are validated against. Defaults to time.Now.
+	Now func() time.Time `json:"-"`
+	// ExpiresAtLeeway allows a token to be used for this long after its `exp` claim.
+	ExpiresAtLeeway time.Duration `json:"-"`
+	// NotBeforeLeeway allows a token to be used for this long before its `nbf` claim.
+	NotBeforeLeeway time.Duration `json:"-"`
+	// NotBeforeOptional accepts tokens without an `nbf` claim, e.g. OpenID Connect ID tokens.
+	// An `nbf` claim which is present is still validated.
+	NotBeforeOptional bool `json:"-"`
+	// IssuedAtLeeway allows the `iat` claim to be this far in the future, to tolerate clock skew
+	// between the issuer and the verifier. It also extends MaxAge.
+	IssuedAtLeeway time.Duration `json:"-"`
+	// MaxAge rejects tokens which were issued (`iat`) longer ago than MaxAge, regardless of
+	// their `exp` claim. Zero disables the check.
+	MaxAge time.Duration `json:"-"`
+
+	// Validators are additional rules, e.g. a required `typ` header or the allowed values of a
+	// custom claim, which run after the registered claims were validated. Their failures are
+	// reported in the same ValidationError, so the token is never marked valid if one fails.
+	Validators []Validator `json:"-"`
+
+	// Revocation is consulted after all claims passed validation to reject revoked tokens.
+	Revocation RevocationChecker `json:"-"`
 }
 
-// validateClaims validates the provided claims of type T, which must satisfy the Claims interface.
-// It checks the claims and collects any validation errors encountered.
-// If no errors are found, it returns nil. Otherwise, it returns a single error containing
-// all validation error messages concatenated together.
-func validateClaims(claims Claims, expected *ExpectedClaims) error {
-	now := time.Now()
-	errs := make([]error, 0, 6)
+// ClaimError describes a single failed validation of the claim named Claim, e.g. `exp`.
+// Err is one of the sentinel errors of this package.
+type ClaimError struct {
+	Claim string
+	Err   error
+}
+
+func (e *ClaimError) Error() string {
+	return e.Err.Error()
+}
 
-	if err := verifyExpiresAt(claims, now); err != nil {
-		errs = append(errs, err)
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when one or more claims of a token failed validation. It keeps
+// every failure, so errors.Is works for each underlying sentinel (e.g. [ErrTokenExpired]) and
+// errors.As can be used to inspect which claims failed.
+type ValidationError struct {
+	Failures []*ClaimError
+}
+
+// Error returns the messages of all failures separated by "; ".
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	for i, failure := range e.Failures {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(failure.Error())
 	}
 
-	if err := verifyNotBefore(claims, now); err != nil {
-		errs = append(errs, err)
+	return sb.String()
+}
+
+// Unwrap returns all failures as *ClaimError, which in turn wrap the sentinel errors.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
 	}
 
-	if err := verifyIssuedAt(claims, now); err != nil {
-		errs = append(errs, err)
+	return errs
+}
+
+// Errors returns the underlying sentinel errors of all failures.
+func (e *ValidationError) Errors() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure.Err)
 	}
 
-	if err := verifyIssuer(claims, expected.Issuer); err != nil {
-		errs = append(errs, err)
+	return errs
+}
+
+// Claims returns the names of the claims which failed validation, without duplicates.
+func (e *ValidationError) Claims() []string {
+	claims := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		if !slices.Contains(claims, failure.Claim) {
+			claims = append(claims, failure.Claim)
+		}
 	}
 
-	if err := verifySubject(claims, expected.Subject); err != nil {
-		errs = append(errs, err)
+	return claims
+}
+
+// HasClaim reports whether the claim with the given name failed validation.
+func (e *ValidationError) HasClaim(claim string) bool {
+	return slices.ContainsFunc(e.Failures, func(failure *ClaimError) bool { return failure.Claim == claim })
+}
+
+// ValidateClaims validates claims against expected exactly like token verification does.
+// It allows other token formats which expose their claims through the Claims interface, such
+// as PASETO, to reuse the validation rules of ExpectedClaims. Validators see an empty header.
+func ValidateClaims(claims Claims, expected *ExpectedClaims) error {
+	return validateClaims(claims, expected)
+}
+
+// validateClaims validates claims of a token without header, see validateTokenClaims.
+func validateClaims(claims Claims, expected *ExpectedClaims) error {
+	return validateTokenClaims(nil, claims, expected)
+}
+
+// validateTokenClaims validates the provided claims of type T, which must satisfy the Claims
+// interface, and runs the validators of expected against the header and claims of the token.
+// It checks the claims and collects any validation errors encountered.
+// If no errors are found, it returns nil. Otherwise, it returns a *ValidationError containing
+// all failed checks.
+func validateTokenClaims(header map[string]any, claims Claims, expected *ExpectedClaims) error {
+	if expected == nil {
+		expected = &ExpectedClaims{}
+	}
+
+	now := time.Now()
+	if expected.Now != nil {
+		now = expected.Now()
+	}
+
+	var failures []*ClaimError
+	check := func(claim string, err error) {
+		if err != nil {
+			failures = append(failures, &ClaimError{Claim: claim, Err: err})
+		}
+	}
+
+	check("exp", verifyExpiresAt(claims, now, expected.ExpiresAtLeeway))
+	if !expected.NotBeforeOptional || claims.GetNotBefore() != nil {
+		check("nbf", verifyNotBefore(claims, now, expected.NotBeforeLeeway))
+	}
+	check("iat", verifyIssuedAt(claims, now, expected.IssuedAtLeeway, expected.MaxAge))
+	check("iss", verifyIssuer(claims, expected.Issuer))
+	check("sub", verifySubject(claims, expected.Subject))
+	check("aud", verifyAudience(claims, expected.Audience))
+
+	if len(expected.Validators) > 0 {
+		ctx := &ValidationContext{Header: header, Claims: claims, Now: now}
+		for _, validator := range expected.Validators {
+			if err := validator(ctx); err != nil {
+				var claimErr *ClaimError
+				if !errors.As(err, &claimErr) {
+					claimErr = &ClaimError{Err: err}
+				}
+				failures = append(failures, claimErr)
+			}
+		}
 	}
 
-	if err := verifyAudience(claims, expected.Audience); err != nil {
-		errs = append(errs, err)
+	if len(failures) == 0 && expected.Revocation != nil {
+		claim, err := revokedClaim(expected.Revocation, claims)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRevocationUnavailable, err)
+		}
+		if claim != "" {
+			check(claim, ErrTokenRevoked)
+		}
 	}
 
-	if len(errs) == 0 {
+	if len(failures) == 0 {
 		return nil
 	}
 
-	var sb strings.Builder
-	for _, err := range errs {
-		sb.WriteString(err.Error() + "; ")
+	return &ValidationError{Failures: failures}
+}
+
+// revokedClaim returns the claim the token carrying claims was revoked by, or an empty string
+// if it is not revoked. Checkers which do not implement [RevokedClaimChecker] report `jti`.
+func revokedClaim(checker RevocationChecker, claims Claims) (string, error) {
+	if c, ok := checker.(RevokedClaimChecker); ok {
+		return c.RevokedClaim(claims)
+	}
+
+	revoked, err := checker.IsRevoked(claims)
+	if err != nil || !revoked {
+		return "", err
 	}
 
-	return errors.New(strings.TrimSuffix(sb.String(), "; "))
+	return "jti", nil
 }
 
 // verifyExpiresAt compares the exp claim in claims against cmp. This function
-// will succeed if cmp < exp.
-func verifyExpiresAt(claims Claims, cmp time.Time) error {
+// will succeed if cmp <= exp + leeway.
+func verifyExpiresAt(claims Claims, cmp time.Time, leeway time.Duration) error {
 	exp := claims.GetExpirationTime()
 
 	if exp == nil {
 		return ErrExpiresAtIsRequired
 	}
 
-	if cmp.After((exp.Time)) {
+	if cmp.After(exp.Add(leeway)) {
 		return ErrTokenExpired
 	}
 
@@ -92,15 +234,15 @@ func verifyExpiresAt(claims Claims, cmp time.Time) error {
 }
 
 // verifyNotBefore compares the nbf claim in claims against cmp. This function
-// will return true if cmp >= nbf.
-func verifyNotBefore(claims Claims, cmp time.Time) error {
+// will succeed if cmp >= nbf - leeway.
+func verifyNotBefore(claims Claims, cmp time.Time, leeway time.Duration) error {
 	nbf := claims.GetNotBefore()
 
 	if nbf == nil {
 		return ErrNotBeforeIsRequired
 	}
 
-	if cmp.Before(nbf.Time) {
+	if cmp.Before(nbf.Add(-leeway)) {
 		return ErrTokenNotValidYet
 	}
 
@@ -108,18 +250,22 @@ func verifyNotBefore(claims Claims, cmp time.Time) error {
 }
 
 // verifyIssuedAt compares the iat claim in claims against cmp. This function
-// will return true if cmp >= iat.
-func verifyIssuedAt(claims Claims, cmp time.Time) error {
+// will succeed if cmp >= iat - leeway and, if maxAge is set, cmp <= iat + maxAge + leeway.
+func verifyIssuedAt(claims Claims, cmp time.Time, leeway time.Duration, maxAge time.Duration) error {
 	iat := claims.GetIssuedAt()
 
 	if iat == nil {
 		return ErrIssuedAtIsRequired
 	}
 
-	if cmp.Before(iat.Time) {
+	if cmp.Before(iat.Add(-leeway)) {
 		return ErrTokenIssuedInFuture
 	}
 
+	if maxAge > 0 && cmp.After(iat.Add(maxAge+leeway)) {
+		return ErrTokenTooOld
+	}
+
 	return nil
 }
 
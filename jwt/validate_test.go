@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"errors"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -92,6 +94,26 @@ func TestValidateClaims_NotYetValid(t *testing.T) {
 	}
 }
 
+func TestValidateClaims_NotBeforeOptional(t *testing.T) {
+	claims := &RegisteredClaims{
+		ExpiresAt: &NumericDate{time.Now().Add(10 * time.Minute)},
+		IssuedAt:  &NumericDate{time.Now().Add(-10 * time.Minute)},
+		Issuer:    "issuer",
+		Subject:   "subject",
+		Audience:  []string{"aud1"},
+	}
+	expected := &ExpectedClaims{Issuer: "issuer", Audience: []string{"aud1"}, NotBeforeOptional: true}
+
+	if err := validateClaims(claims, expected); err != nil {
+		t.Fatalf("Expected missing nbf to be accepted, got %v", err)
+	}
+
+	claims.NotBefore = &NumericDate{time.Now().Add(10 * time.Minute)}
+	if err := validateClaims(claims, expected); !errors.Is(err, ErrTokenNotValidYet) {
+		t.Errorf("Expected present nbf to be validated, got %v", err)
+	}
+}
+
 func TestValidateClaims_IssuedInFuture(t *testing.T) {
 	err := validateClaims(&RegisteredClaims{
 		ID:        "12345",
@@ -183,3 +205,141 @@ func TestValidateClaims_AudienceMismatch(t *testing.T) {
 		t.Errorf("Expected error message to contain %q, got %q", ErrAudienceMismatch.Error(), err.Error())
 	}
 }
+
+func TestValidateClaims_InjectedClock(t *testing.T) {
+	issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	claims := &RegisteredClaims{
+		ExpiresAt: &NumericDate{issuedAt.Add(time.Hour)},
+		NotBefore: &NumericDate{issuedAt},
+		IssuedAt:  &NumericDate{issuedAt},
+		Issuer:    "issuer",
+		Subject:   "subject",
+		Audience:  []string{"aud"},
+	}
+
+	expected := &ExpectedClaims{Issuer: "issuer", Audience: []string{"aud"}}
+
+	expected.Now = func() time.Time { return issuedAt.Add(30 * time.Minute) }
+	if err := validateClaims(claims, expected); err != nil {
+		t.Errorf("expected no error within lifetime, got %v", err)
+	}
+
+	expected.Now = func() time.Time { return issuedAt.Add(2 * time.Hour) }
+	if err := validateClaims(claims, expected); err == nil || !strings.Contains(err.Error(), ErrTokenExpired.Error()) {
+		t.Errorf("expected ErrTokenExpired after lifetime, got %v", err)
+	}
+}
+
+func TestValidateClaims_Leeway(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expected := &ExpectedClaims{
+		Issuer:          "issuer",
+		Audience:        []string{"aud"},
+		Now:             func() time.Time { return now },
+		ExpiresAtLeeway: 5 * time.Second,
+		NotBeforeLeeway: 5 * time.Second,
+		IssuedAtLeeway:  5 * time.Second,
+	}
+
+	// Issued by a pod whose clock is three seconds ahead.
+	skewed := &RegisteredClaims{
+		ExpiresAt: &NumericDate{now.Add(-3 * time.Second)},
+		NotBefore: &NumericDate{now.Add(3 * time.Second)},
+		IssuedAt:  &NumericDate{now.Add(3 * time.Second)},
+		Issuer:    "issuer",
+		Subject:   "subject",
+		Audience:  []string{"aud"},
+	}
+	if err := validateClaims(skewed, expected); err != nil {
+		t.Errorf("expected no error within leeway, got %v", err)
+	}
+
+	tooSkewed := &RegisteredClaims{
+		ExpiresAt: &NumericDate{now.Add(-10 * time.Second)},
+		NotBefore: &NumericDate{now.Add(10 * time.Second)},
+		IssuedAt:  &NumericDate{now.Add(10 * time.Second)},
+		Issuer:    "issuer",
+		Subject:   "subject",
+		Audience:  []string{"aud"},
+	}
+	err := validateClaims(tooSkewed, expected)
+	if err == nil {
+		t.Fatal("expected errors outside of leeway")
+	}
+	for _, substr := range []string{ErrTokenExpired.Error(), ErrTokenNotValidYet.Error(), ErrTokenIssuedInFuture.Error()} {
+		if !strings.Contains(err.Error(), substr) {
+			t.Errorf("expected error message to contain %q, got %q", substr, err.Error())
+		}
+	}
+}
+
+func TestValidateClaims_MaxAge(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	claims := &RegisteredClaims{
+		ExpiresAt: &NumericDate{now.Add(time.Hour)},
+		NotBefore: &NumericDate{now.Add(-20 * time.Minute)},
+		IssuedAt:  &NumericDate{now.Add(-20 * time.Minute)},
+		Issuer:    "issuer",
+		Subject:   "subject",
+		Audience:  []string{"aud"},
+	}
+
+	expected := &ExpectedClaims{Issuer: "issuer", Audience: []string{"aud"}, Now: func() time.Time { return now }, MaxAge: 30 * time.Minute}
+	if err := validateClaims(claims, expected); err != nil {
+		t.Errorf("expected no error within max age, got %v", err)
+	}
+
+	expected.MaxAge = 10 * time.Minute
+	if err := validateClaims(claims, expected); err == nil || !strings.Contains(err.Error(), ErrTokenTooOld.Error()) {
+		t.Errorf("expected ErrTokenTooOld, got %v", err)
+	}
+}
+
+func TestValidateClaims_ValidationError(t *testing.T) {
+	err := validateClaims(&RegisteredClaims{
+		ExpiresAt: &NumericDate{time.Now().Add(-10 * time.Minute)},
+		NotBefore: &NumericDate{time.Now().Add(-10 * time.Minute)},
+		IssuedAt:  &NumericDate{time.Now().Add(-10 * time.Minute)},
+		Issuer:    "issuer1",
+		Subject:   "subject",
+		Audience:  []string{"aud1"},
+	}, &ExpectedClaims{
+		Issuer:   "issuer2",
+		Audience: []string{"aud1"},
+	})
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError, got %T", err)
+	}
+
+	if !errors.Is(err, ErrTokenExpired) {
+		t.Error("expected errors.Is to match ErrTokenExpired")
+	}
+	if !errors.Is(err, ErrIssuerMismatch) {
+		t.Error("expected errors.Is to match ErrIssuerMismatch")
+	}
+	if errors.Is(err, ErrAudienceMismatch) {
+		t.Error("expected errors.Is not to match ErrAudienceMismatch")
+	}
+
+	if claims := validationErr.Claims(); !slices.Equal(claims, []string{"exp", "iss"}) {
+		t.Errorf("expected failed claims [exp iss], got %v", claims)
+	}
+	if !validationErr.HasClaim("exp") || validationErr.HasClaim("aud") {
+		t.Error("unexpected result of HasClaim")
+	}
+	if len(validationErr.Errors()) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(validationErr.Errors()))
+	}
+
+	expectedMessage := ErrTokenExpired.Error() + "; " + ErrIssuerMismatch.Error()
+	if err.Error() != expectedMessage {
+		t.Errorf("expected message %q, got %q", expectedMessage, err.Error())
+	}
+
+	var claimErr *ClaimError
+	if !errors.As(err, &claimErr) || claimErr.Claim != "exp" {
+		t.Errorf("expected first *ClaimError to be for 'exp', got %+v", claimErr)
+	}
+}
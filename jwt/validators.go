@@ -0,0 +1,158 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenInvalidType     = errors.New("jwt: token has an invalid 'typ' header")
+	ErrClaimIsRequired      = errors.New("jwt: claim is required")
+	ErrClaimValueNotAllowed = errors.New("jwt: claim has a value which is not allowed")
+	ErrClaimInvalid         = errors.New("jwt: claim is invalid")
+)
+
+// Validator is an additional validation rule configured in [ExpectedClaims.Validators]. It
+// returns nil if the token passes the rule. Errors should be a *ClaimError naming the claim
+// which failed, any other error is reported as a failure without claim name.
+type Validator func(ctx *ValidationContext) error
+
+// ValidationContext gives a Validator access to the token being validated.
+type ValidationContext struct {
+	// Header is the decoded header of the token, nil for formats without a JOSE header.
+	Header map[string]any
+	// Claims are the decoded claims of the token, as passed to the verify function.
+	Claims Claims
+	// Now is the time the token is validated at, see [ExpectedClaims.Now].
+	Now time.Time
+
+	raw map[string]json.RawMessage
+}
+
+// Claim returns the raw JSON value of the claim with the given name. Claims whose value is
+// null are reported as missing. It works for every claims type, including custom structs, by
+// looking at the JSON representation of the claims.
+func (ctx *ValidationContext) Claim(name string) (json.RawMessage, bool) {
+	if ctx.raw == nil {
+		ctx.raw = make(map[string]json.RawMessage)
+		if b, err := json.Marshal(ctx.Claims); err == nil {
+			json.Unmarshal(b, &ctx.raw)
+		}
+	}
+
+	value, ok := ctx.raw[name]
+	if !ok || string(value) == "null" {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// ClaimValue decodes the claim with the given name into a value of type V. It returns false if
+// the claim is missing or cannot be decoded into V.
+func ClaimValue[V any](ctx *ValidationContext, name string) (V, bool) {
+	var value V
+
+	raw, ok := ctx.Claim(name)
+	if !ok {
+		return value, false
+	}
+
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, false
+	}
+
+	return value, true
+}
+
+// RequireType requires the `typ` header to be one of the given media types, e.g. "JWT" or
+// "at+jwt". As recommended in https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.9 the
+// comparison is case-insensitive and ignores an "application/" prefix.
+func RequireType(types ...string) Validator {
+	return func(ctx *ValidationContext) error {
+		typ, _ := ctx.Header["typ"].(string)
+		for _, t := range types {
+			if typ != "" && strings.EqualFold(trimMediaType(typ), trimMediaType(t)) {
+				return nil
+			}
+		}
+
+		return &ClaimError{Claim: "typ", Err: ErrTokenInvalidType}
+	}
+}
+
+// RequireClaims requires every claim with one of the given names to be present and not null.
+func RequireClaims(names ...string) Validator {
+	return func(ctx *ValidationContext) error {
+		for _, name := range names {
+			if _, ok := ctx.Claim(name); !ok {
+				return &ClaimError{Claim: name, Err: ErrClaimIsRequired}
+			}
+		}
+
+		return nil
+	}
+}
+
+// ClaimOneOf requires the claim with the given name to be present and equal to one of values.
+func ClaimOneOf[V comparable](name string, values ...V) Validator {
+	return func(ctx *ValidationContext) error {
+		if _, ok := ctx.Claim(name); !ok {
+			return &ClaimError{Claim: name, Err: ErrClaimIsRequired}
+		}
+
+		value, ok := ClaimValue[V](ctx, name)
+		if !ok || !slices.Contains(values, value) {
+			return &ClaimError{Claim: name, Err: ErrClaimValueNotAllowed}
+		}
+
+		return nil
+	}
+}
+
+// ClaimPredicate requires the claim with the given name to be present, decodable into V and
+// to satisfy valid.
+func ClaimPredicate[V any](name string, valid func(value V) bool) Validator {
+	return func(ctx *ValidationContext) error {
+		if _, ok := ctx.Claim(name); !ok {
+			return &ClaimError{Claim: name, Err: ErrClaimIsRequired}
+		}
+
+		value, ok := ClaimValue[V](ctx, name)
+		if !ok || !valid(value) {
+			return &ClaimError{Claim: name, Err: ErrClaimInvalid}
+		}
+
+		return nil
+	}
+}
+
+// Check runs check against the claims if they are of the custom claims type T (or *T), which
+// allows rules on typed fields without going through JSON. Tokens with claims of a different
+// type fail validation.
+func Check[T Claims](check func(claims T) error) Validator {
+	return func(ctx *ValidationContext) error {
+		switch claims := any(ctx.Claims).(type) {
+		case T:
+			return check(claims)
+		case *T:
+			if claims != nil {
+				return check(*claims)
+			}
+		}
+
+		return &ClaimError{Err: ErrClaimInvalid}
+	}
+}
+
+// trimMediaType removes the optional "application/" prefix of a `typ` header value.
+func trimMediaType(typ string) string {
+	if len(typ) > len("application/") && strings.EqualFold(typ[:len("application/")], "application/") {
+		return typ[len("application/"):]
+	}
+
+	return typ
+}
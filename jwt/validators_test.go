@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validatorTestClaims struct {
+	Email  string   `json:"email,omitempty"`
+	Role   string   `json:"role,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	RegisteredClaims
+}
+
+// verifyValidatorTestToken signs claims with the given extra header parameters and verifies
+// them with validators.
+func verifyValidatorTestToken(t *testing.T, header map[string]any, claims *validatorTestClaims, validators ...Validator) (*Token[validatorTestClaims], error) {
+	t.Helper()
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pub, priv := PublicKey(publicKey), PrivateKey(privateKey)
+
+	token := NewToken(claims)
+	for k, v := range header {
+		token.Header[k] = v
+	}
+
+	signed, err := token.SignedString(&priv)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	expected := validTestExpectedClaims()
+	expected.Validators = validators
+
+	return VerifyToken[validatorTestClaims](signed, &pub, expected)
+}
+
+func newValidatorTestClaims() *validatorTestClaims {
+	return &validatorTestClaims{
+		Email:            "user@loggd.me",
+		Role:             "admin",
+		Scopes:           []string{"read", "write"},
+		RegisteredClaims: *validTestClaims(),
+	}
+}
+
+func TestValidators_Pass(t *testing.T) {
+	token, err := verifyValidatorTestToken(t, map[string]any{"typ": "application/at+JWT"}, newValidatorTestClaims(),
+		RequireType("at+jwt"),
+		RequireClaims("email", "role"),
+		ClaimOneOf("role", "admin", "editor"),
+		ClaimPredicate("scopes", func(scopes []string) bool { return len(scopes) == 2 }),
+		Check(func(claims validatorTestClaims) error {
+			if !strings.HasSuffix(claims.Email, "@loggd.me") {
+				return &ClaimError{Claim: "email", Err: ErrClaimInvalid}
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !token.Valid {
+		t.Error("expected token to be valid")
+	}
+}
+
+func TestValidators_Fail(t *testing.T) {
+	tests := map[string]struct {
+		validator Validator
+		claim     string
+		err       error
+	}{
+		"typ mismatch":      {validator: RequireType("at+jwt"), claim: "typ", err: ErrTokenInvalidType},
+		"missing claim":     {validator: RequireClaims("email", "tenant"), claim: "tenant", err: ErrClaimIsRequired},
+		"value not allowed": {validator: ClaimOneOf("role", "editor", "viewer"), claim: "role", err: ErrClaimValueNotAllowed},
+		"one of missing":    {validator: ClaimOneOf("tenant", "a"), claim: "tenant", err: ErrClaimIsRequired},
+		"wrong value type":  {validator: ClaimOneOf("scopes", "read"), claim: "scopes", err: ErrClaimValueNotAllowed},
+		"predicate false": {
+			validator: ClaimPredicate("email", func(email string) bool { return strings.HasSuffix(email, "@example.com") }),
+			claim:     "email",
+			err:       ErrClaimInvalid,
+		},
+		"check failed": {
+			validator: Check(func(claims validatorTestClaims) error {
+				return &ClaimError{Claim: "email", Err: ErrClaimInvalid}
+			}),
+			claim: "email",
+			err:   ErrClaimInvalid,
+		},
+		"check wrong type": {
+			validator: Check(func(claims RegisteredClaims) error { return nil }),
+			claim:     "",
+			err:       ErrClaimInvalid,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, err := verifyValidatorTestToken(t, nil, newValidatorTestClaims(), tt.validator)
+			if token != nil {
+				t.Error("expected no token to be returned")
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !validationErr.HasClaim(tt.claim) {
+				t.Errorf("expected validation error for claim %q, got %v", tt.claim, err)
+			}
+		})
+	}
+}
+
+func TestValidators_PlainErrorAndRegisteredFailuresAreCollected(t *testing.T) {
+	claims := newValidatorTestClaims()
+	claims.Issuer = "https://other.example.com"
+
+	plain := errors.New("custom failure")
+	_, err := verifyValidatorTestToken(t, nil, claims, func(*ValidationContext) error { return plain })
+
+	if !errors.Is(err, plain) || !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("expected custom failure and ErrIssuerMismatch, got %v", err)
+	}
+}
+
+func TestClaimValue(t *testing.T) {
+	ctx := &ValidationContext{Claims: newValidatorTestClaims()}
+
+	if email, ok := ClaimValue[string](ctx, "email"); !ok || email != "user@loggd.me" {
+		t.Errorf("expected email claim, got %q (%v)", email, ok)
+	}
+	if exp, ok := ClaimValue[NumericDate](ctx, "exp"); !ok || exp.IsZero() {
+		t.Errorf("expected exp claim, got %v (%v)", exp, ok)
+	}
+	if _, ok := ClaimValue[int](ctx, "email"); ok {
+		t.Error("expected decoding a string claim into int to fail")
+	}
+	if _, ok := ClaimValue[string](ctx, "jti"); ok {
+		t.Error("expected omitted claim to be missing")
+	}
+}
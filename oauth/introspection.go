@@ -0,0 +1,311 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+var (
+	ErrIntrospectionNoKey = errors.New("oauth: introspector requires a Key or KeySet")
+)
+
+// IntrospectionResponse is the response of a token introspection endpoint, see
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.2. Inactive tokens only carry
+// Active set to false.
+type IntrospectionResponse struct {
+	Active    bool             `json:"active"`
+	Scope     string           `json:"scope,omitempty"`
+	ClientID  string           `json:"client_id,omitempty"`
+	Username  string           `json:"username,omitempty"`
+	TokenType string           `json:"token_type,omitempty"`
+	ExpiresAt *jwt.NumericDate `json:"exp,omitempty"`
+	IssuedAt  *jwt.NumericDate `json:"iat,omitempty"`
+	NotBefore *jwt.NumericDate `json:"nbf,omitempty"`
+	Subject   string           `json:"sub,omitempty"`
+	Audience  jwt.Audience     `json:"aud,omitempty"`
+	Issuer    string           `json:"iss,omitempty"`
+	ID        string           `json:"jti,omitempty"`
+}
+
+// TokenIntrospector looks up the state of a token. Implementations return a nil response or
+// a response with Active set to false for unknown, expired or revoked tokens, an error should
+// only be returned if the state of the token could not be determined.
+type TokenIntrospector interface {
+	Introspect(token string, tokenTypeHint string) (*IntrospectionResponse, error)
+}
+
+// introspectionClaims are the claims of a JWT access token which are reported by JWTIntrospector.
+type introspectionClaims struct {
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWTIntrospector is a TokenIntrospector for JWT access tokens signed with Key or, if Key is
+// nil, with one of the keys of KeySet. Tokens are verified with [jwt.VerifyToken], so expired
+// or revoked tokens (see [jwt.ExpectedClaims.Revocation]) are reported as inactive.
+// Introspect returns ErrIntrospectionNoKey if neither Key nor KeySet is set.
+type JWTIntrospector struct {
+	Key      *jwt.PublicKey
+	KeySet   *jwt.KeySet
+	Expected *jwt.ExpectedClaims
+}
+
+// Introspect implements the TokenIntrospector interface.
+func (i *JWTIntrospector) Introspect(token string, tokenTypeHint string) (*IntrospectionResponse, error) {
+	var parsed *jwt.Token[introspectionClaims]
+	var err error
+
+	switch {
+	case i.Key != nil:
+		parsed, err = jwt.VerifyToken[introspectionClaims](token, i.Key, i.Expected)
+	case i.KeySet != nil:
+		parsed, err = jwt.VerifyTokenWithKeySet[introspectionClaims](token, i.KeySet, i.Expected)
+	default:
+		return nil, ErrIntrospectionNoKey
+	}
+
+	if errors.Is(err, jwt.ErrRevocationUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	claims := parsed.Claims
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		NotBefore: claims.NotBefore,
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		ID:        claims.ID,
+	}, nil
+}
+
+// IntrospectionHandler serves a token introspection endpoint as described in
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2. Only callers accepted by
+// Authenticate, usually resource servers, may introspect tokens.
+type IntrospectionHandler struct {
+	Introspector TokenIntrospector
+	Authenticate func(r *http.Request) bool
+}
+
+// NewIntrospectionHandler creates and returns a new IntrospectionHandler which looks up tokens
+// with introspector and authenticates callers with authenticate, e.g. [BasicClientAuthenticator].
+func NewIntrospectionHandler(introspector TokenIntrospector, authenticate func(r *http.Request) bool) *IntrospectionHandler {
+	return &IntrospectionHandler{Introspector: introspector, Authenticate: authenticate}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *IntrospectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.Authenticate == nil || !h.Authenticate(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="introspection"`)
+		writeNoStoreJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		writeNoStoreJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	response, err := h.Introspector.Introspect(token, r.PostFormValue("token_type_hint"))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if response == nil || !response.Active {
+		response = &IntrospectionResponse{Active: false}
+	}
+
+	writeNoStoreJSON(w, http.StatusOK, response)
+}
+
+// BasicClientAuthenticator returns an authenticate function for [NewIntrospectionHandler] which
+// accepts callers presenting one of the given client ID and secret pairs with HTTP Basic
+// authentication.
+func BasicClientAuthenticator(clients map[string]string) func(r *http.Request) bool {
+	hashes := make(map[string][32]byte, len(clients))
+	for clientID, secret := range clients {
+		hashes[clientID] = sha256.Sum256([]byte(secret))
+	}
+
+	return func(r *http.Request) bool {
+		clientID, secret, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+
+		expected, known := hashes[clientID]
+		presented := sha256.Sum256([]byte(secret))
+
+		return subtle.ConstantTimeCompare(expected[:], presented[:]) == 1 && known
+	}
+}
+
+// writeNoStoreJSON writes body as JSON response which must not be cached by intermediaries.
+func writeNoStoreJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// IntrospectionClient calls a remote token introspection endpoint, authenticating with the
+// client credentials of a resource server. Responses are cached for CacheTTL, but active
+// tokens never beyond their `exp`. The cache is keyed by the SHA-256 hash of the token, so
+// tokens are not kept in memory, and expired entries are pruned at most once per CacheTTL.
+// An IntrospectionClient is safe for concurrent use.
+type IntrospectionClient struct {
+	Endpoint     string
+	ClientID     string
+	ClientSecret string
+	Http         *http.Client
+	CacheTTL     time.Duration
+
+	mu       sync.Mutex
+	cache    map[string]cachedIntrospection
+	prunedAt time.Time
+	now      func() time.Time
+}
+
+type cachedIntrospection struct {
+	response  *IntrospectionResponse
+	expiresAt time.Time
+}
+
+// NewIntrospectionClient creates and returns a new IntrospectionClient for the introspection
+// endpoint. Responses are cached for one minute unless CacheTTL is changed, zero disables
+// the cache.
+func NewIntrospectionClient(endpoint string, clientID string, clientSecret string) *IntrospectionClient {
+	return &IntrospectionClient{
+		Endpoint:     endpoint,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Http:         &http.Client{},
+		CacheTTL:     time.Minute,
+		now:          time.Now,
+	}
+}
+
+// Introspect implements the TokenIntrospector interface by calling the remote endpoint. The
+// tokenTypeHint is optional and may be empty.
+func (c *IntrospectionClient) Introspect(token string, tokenTypeHint string) (*IntrospectionResponse, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	if response, ok := c.cached(key); ok {
+		return response, nil
+	}
+
+	body := url.Values{}
+	body.Set("token", token)
+	if tokenTypeHint != "" {
+		body.Set("token_type_hint", tokenTypeHint)
+	}
+
+	request, err := CreateOAuth2Request(c.Endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedCredentials := EncodeBasicCredentials(c.ClientID, c.ClientSecret)
+	request.Header.Set("Authorization", fmt.Sprintf("Basic %s", encodedCredentials))
+
+	client := c.Http
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := SendTokenRequest[IntrospectionResponse](request, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.Active {
+		response = &IntrospectionResponse{Active: false}
+	}
+
+	c.store(key, response)
+
+	return response, nil
+}
+
+// cached returns the cached response for the token hash if it did not expire yet.
+func (c *IntrospectionClient) cached(key string) (*IntrospectionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[key]
+	if !ok || !c.clock().Before(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+// store caches the response for the token hash. Expired entries are pruned if the last
+// pruning is at least CacheTTL ago.
+func (c *IntrospectionClient) store(key string, response *IntrospectionResponse) {
+	if c.CacheTTL <= 0 {
+		return
+	}
+
+	now := c.clock()
+	expiresAt := now.Add(c.CacheTTL)
+	if response.Active && response.ExpiresAt != nil && response.ExpiresAt.Before(expiresAt) {
+		expiresAt = response.ExpiresAt.Time
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cache == nil {
+		c.cache = make(map[string]cachedIntrospection)
+	}
+
+	if now.Sub(c.prunedAt) >= c.CacheTTL {
+		c.prunedAt = now
+		for k, entry := range c.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+	}
+
+	c.cache[key] = cachedIntrospection{response: response, expiresAt: expiresAt}
+}
+
+// clock returns the current time, also for clients which were not created with
+// [NewIntrospectionClient].
+func (c *IntrospectionClient) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+
+	return c.now()
+}
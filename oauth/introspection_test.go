@@ -0,0 +1,230 @@
+package oauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+func newIntrospectionTestKeys(t *testing.T) (*jwt.PrivateKey, *jwt.PublicKey) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	pub, priv := jwt.PublicKey(publicKey), jwt.PrivateKey(privateKey)
+	return &priv, &pub
+}
+
+func signIntrospectionTestToken(t *testing.T, key *jwt.PrivateKey, expiresAt time.Time) string {
+	t.Helper()
+
+	signed, err := jwt.NewToken(&introspectionClaims{
+		Scope:    "read write",
+		ClientID: "client-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://issuer.example.com",
+			Subject:   "user-1",
+			Audience:  jwt.Audience{"api"},
+			ExpiresAt: &jwt.NumericDate{Time: expiresAt},
+			NotBefore: &jwt.NumericDate{Time: time.Now()},
+			IssuedAt:  &jwt.NumericDate{Time: time.Now()},
+		},
+	}).SignedString(key)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	return signed
+}
+
+var introspectionTestExpectedClaims = &jwt.ExpectedClaims{Issuer: "https://issuer.example.com", Audience: []string{"api"}}
+
+func TestJWTIntrospector(t *testing.T) {
+	privateKey, publicKey := newIntrospectionTestKeys(t)
+	introspector := &JWTIntrospector{Key: publicKey, Expected: introspectionTestExpectedClaims}
+
+	response, err := introspector.Introspect(signIntrospectionTestToken(t, privateKey, time.Now().Add(time.Hour)), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !response.Active || response.Subject != "user-1" || response.Scope != "read write" || response.ClientID != "client-1" {
+		t.Errorf("expected active response for user-1, got %+v", response)
+	}
+
+	response, err = introspector.Introspect(signIntrospectionTestToken(t, privateKey, time.Now().Add(-time.Hour)), "")
+	if err != nil || response.Active {
+		t.Errorf("expected inactive response for expired token, got %+v, %v", response, err)
+	}
+
+	if _, err := (&JWTIntrospector{}).Introspect("token", ""); !errors.Is(err, ErrIntrospectionNoKey) {
+		t.Errorf("expected ErrIntrospectionNoKey, got %v", err)
+	}
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	privateKey, publicKey := newIntrospectionTestKeys(t)
+	handler := NewIntrospectionHandler(&JWTIntrospector{Key: publicKey, Expected: introspectionTestExpectedClaims}, BasicClientAuthenticator(map[string]string{"rs": "secret"}))
+	token := signIntrospectionTestToken(t, privateKey, time.Now().Add(time.Hour))
+
+	tests := map[string]struct {
+		method   string
+		username string
+		password string
+		token    string
+		status   int
+		active   bool
+	}{
+		"wrong method":   {method: http.MethodGet, username: "rs", password: "secret", token: token, status: http.StatusMethodNotAllowed},
+		"unknown client": {method: http.MethodPost, username: "rs", password: "wrong", token: token, status: http.StatusUnauthorized},
+		"missing token":  {method: http.MethodPost, username: "rs", password: "secret", status: http.StatusBadRequest},
+		"invalid token":  {method: http.MethodPost, username: "rs", password: "secret", token: "invalid", status: http.StatusOK},
+		"active token":   {method: http.MethodPost, username: "rs", password: "secret", token: token, status: http.StatusOK, active: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.token != "" {
+				form.Set("token", tt.token)
+			}
+
+			req := httptest.NewRequest(tt.method, "/introspect", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(tt.username, tt.password)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("expected no-store response, got %q", rec.Header().Get("Cache-Control"))
+			}
+
+			var response IntrospectionResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("unexpected error decoding: %v", err)
+			}
+			if response.Active != tt.active {
+				t.Errorf("expected active %v, got %+v", tt.active, response)
+			}
+			if !tt.active && response.Subject != "" {
+				t.Errorf("expected inactive response without claims, got %+v", response)
+			}
+		})
+	}
+}
+
+func newIntrospectionTestServer(t *testing.T, response IntrospectionResponse) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		if clientID, secret, ok := r.BasicAuth(); !ok || clientID != "rs" || secret != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token") == "" {
+			http.Error(w, "missing token", http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestIntrospectionClient_Cache(t *testing.T) {
+	now := time.Now()
+	server, calls := newIntrospectionTestServer(t, IntrospectionResponse{
+		Active:    true,
+		Subject:   "user-1",
+		ExpiresAt: &jwt.NumericDate{Time: now.Add(30 * time.Second)},
+	})
+
+	client := NewIntrospectionClient(server.URL, "rs", "secret")
+	client.now = func() time.Time { return now }
+
+	for range 2 {
+		response, err := client.Introspect("token-1", "access_token")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !response.Active || response.Subject != "user-1" {
+			t.Errorf("expected active response for user-1, got %+v", response)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected second lookup to be cached, got %d calls", calls.Load())
+	}
+
+	// Active tokens are never cached beyond their exp, even if CacheTTL is longer.
+	now = now.Add(45 * time.Second)
+	client.Introspect("token-1", "")
+	if calls.Load() != 2 {
+		t.Errorf("expected expired cache entry to be refetched, got %d calls", calls.Load())
+	}
+}
+
+func TestIntrospectionClient_PrunesPeriodically(t *testing.T) {
+	now := time.Now()
+	server, _ := newIntrospectionTestServer(t, IntrospectionResponse{Active: false})
+
+	client := NewIntrospectionClient(server.URL, "rs", "secret")
+	client.now = func() time.Time { return now }
+
+	client.Introspect("token-1", "")
+
+	now = now.Add(client.CacheTTL + time.Second)
+	client.Introspect("token-2", "")
+	if len(client.cache) != 1 {
+		t.Errorf("expected expired entry to be pruned, got %d entries", len(client.cache))
+	}
+
+	now = now.Add(client.CacheTTL + time.Second)
+	client.Introspect("token-3", "")
+	client.Introspect("token-4", "")
+	if len(client.cache) != 2 {
+		t.Errorf("expected pruning at most once per CacheTTL, got %d entries", len(client.cache))
+	}
+}
+
+func TestIntrospectionClient_StructLiteral(t *testing.T) {
+	server, calls := newIntrospectionTestServer(t, IntrospectionResponse{Active: true, Subject: "user-1"})
+
+	client := &IntrospectionClient{Endpoint: server.URL, ClientID: "rs", ClientSecret: "secret", CacheTTL: time.Minute}
+	for range 2 {
+		if response, err := client.Introspect("token-1", ""); err != nil || !response.Active {
+			t.Fatalf("expected active response, got %+v, %v", response, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected second lookup to be cached, got %d calls", calls.Load())
+	}
+
+	client.ClientSecret = "wrong"
+	if _, err := client.Introspect("token-2", ""); !errors.Is(err, ErrOauthRequest) {
+		t.Errorf("expected ErrOauthRequest for rejected credentials, got %v", err)
+	}
+}
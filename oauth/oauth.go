@@ -52,6 +52,7 @@ type OAuth2User struct {
 
 type OAuth2Tokens struct {
 	AccessToken string
+	TokenType   string
 	IdToken     *string
 }
 
@@ -62,6 +63,9 @@ type OAuth2Client struct {
 	ClientSecret string
 	RedirectURI  *string
 	Http         *http.Client
+	// DPoP, if set, sends a DPoP proof (https://datatracker.ietf.org/doc/html/rfc9449) with every
+	// token request, so the issued tokens are bound to this key.
+	DPoP *jwt.DPoPKey
 }
 
 // NewOauthProvider creates and returns a new instance of OAuth2Client with the provided
@@ -145,15 +149,7 @@ func (p *OAuth2Client) ValidateAuthorizationCode(endpoint string, code string, c
 		body.Set("code_verifier", *codeVerifier)
 	}
 
-	request, err := CreateOAuth2Request(endpoint, body)
-	if err != nil {
-		return nil, err
-	}
-
-	encodedCredentials := EncodeBasicCredentials(p.ClientID, p.ClientSecret)
-	request.Header.Set("Authorization", fmt.Sprintf("Basic %s", encodedCredentials))
-
-	tokensMap, err := SendTokenRequest[map[string]any](request, p.Http)
+	tokensMap, err := p.sendTokenRequest(endpoint, body)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +160,10 @@ func (p *OAuth2Client) ValidateAuthorizationCode(endpoint string, code string, c
 		oauth2Tokens.AccessToken = accessToken
 	}
 
+	if tokenType, ok := (*tokensMap)["token_type"].(string); ok {
+		oauth2Tokens.TokenType = tokenType
+	}
+
 	if idToken, ok := (*tokensMap)["id_token"].(string); ok {
 		oauth2Tokens.IdToken = &idToken
 	}
@@ -171,6 +171,47 @@ func (p *OAuth2Client) ValidateAuthorizationCode(endpoint string, code string, c
 	return oauth2Tokens, nil
 }
 
+// sendTokenRequest sends a token request authenticated with the client credentials. If the
+// client has a DPoP key, a proof is attached and the request is retried once with the nonce
+// the server demands in the `DPoP-Nonce` header, see
+// https://datatracker.ietf.org/doc/html/rfc9449#section-8.
+func (p *OAuth2Client) sendTokenRequest(endpoint string, body url.Values) (*map[string]any, error) {
+	var nonce string
+
+	for attempt := 0; ; attempt++ {
+		request, err := CreateOAuth2Request(endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+
+		encodedCredentials := EncodeBasicCredentials(p.ClientID, p.ClientSecret)
+		request.Header.Set("Authorization", fmt.Sprintf("Basic %s", encodedCredentials))
+
+		if p.DPoP == nil {
+			return SendTokenRequest[map[string]any](request, p.Http)
+		}
+
+		proof, err := p.DPoP.Proof(http.MethodPost, endpoint, "", nonce)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("DPoP", proof)
+
+		resp, err := p.Http.Do(request)
+		if err != nil {
+			return nil, ErrTokenFetch
+		}
+
+		if resp.StatusCode == http.StatusBadRequest && attempt == 0 && resp.Header.Get("DPoP-Nonce") != "" {
+			resp.Body.Close()
+			nonce = resp.Header.Get("DPoP-Nonce")
+			continue
+		}
+
+		return decodeTokenResponse[map[string]any](resp)
+	}
+}
+
 // CreateOAuth2Request constructs an HTTP POST request for OAuth2 endpoints with the given URL and form-encoded body.
 // It sets appropriate headers for content type, accept, user agent, and content length.
 // Returns the constructed *http.Request or an error if the request could not be created.
@@ -199,9 +240,15 @@ func SendTokenRequest[T any](req *http.Request, client *http.Client) (*T, error)
 	if err != nil {
 		return nil, ErrTokenFetch
 	}
+
+	return decodeTokenResponse[T](resp)
+}
+
+// decodeTokenResponse decodes the JSON body of a successful token response into a value of
+// type T and closes the body.
+func decodeTokenResponse[T any](resp *http.Response) (*T, error) {
 	defer resp.Body.Close()
 
-	fmt.Println(resp.StatusCode)
 	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
 		return nil, ErrOauthRequest
 	}
@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+// newDPoPTestServer serves a token endpoint which requires DPoP proofs carrying the nonce
+// "nonce-1", as described in https://datatracker.ietf.org/doc/html/rfc9449#section-8. Unless
+// acceptNonce is false, proofs with the nonce are answered with an access token. The returned
+// function lists the verified proofs of all requests.
+func newDPoPTestServer(t *testing.T, acceptNonce bool) (*httptest.Server, func() []*jwt.DPoPProof) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var proofs []*jwt.DPoPProof
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proof, err := jwt.NewDPoPVerifier(nil).Verify(r.Header.Get("DPoP"), r.Method, server.URL, "")
+		if err != nil {
+			writeNoStoreJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_dpop_proof"})
+			return
+		}
+
+		mu.Lock()
+		proofs = append(proofs, proof)
+		mu.Unlock()
+
+		if !acceptNonce || proof.Claims.Nonce != "nonce-1" {
+			w.Header().Set("DPoP-Nonce", "nonce-1")
+			writeNoStoreJSON(w, http.StatusBadRequest, map[string]string{"error": "use_dpop_nonce"})
+			return
+		}
+
+		writeNoStoreJSON(w, http.StatusOK, map[string]string{"access_token": "access-1", "token_type": "DPoP"})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []*jwt.DPoPProof {
+		mu.Lock()
+		defer mu.Unlock()
+		return proofs
+	}
+}
+
+func TestSendTokenRequest_RetriesWithDPoPNonce(t *testing.T) {
+	server, proofs := newDPoPTestServer(t, true)
+
+	key, err := jwt.GenerateDPoPKey("EdDSA")
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	client := NewOauthProvider("client-1", "secret", nil)
+	client.DPoP = key
+
+	tokens, err := client.ValidateAuthorizationCode(server.URL, "code-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokens.AccessToken != "access-1" || tokens.TokenType != "DPoP" {
+		t.Errorf("expected DPoP access token, got %+v", tokens)
+	}
+
+	sent := proofs()
+	if len(sent) != 2 {
+		t.Fatalf("expected one retry, got %d requests", len(sent))
+	}
+	if sent[0].Claims.Nonce != "" {
+		t.Errorf("expected first proof without nonce, got %q", sent[0].Claims.Nonce)
+	}
+	if sent[1].Claims.Nonce != "nonce-1" {
+		t.Errorf("expected second proof to carry the nonce, got %q", sent[1].Claims.Nonce)
+	}
+	if sent[0].Claims.ID == sent[1].Claims.ID {
+		t.Error("expected a fresh jti for the retried proof")
+	}
+}
+
+func TestSendTokenRequest_RetriesDPoPNonceOnce(t *testing.T) {
+	server, proofs := newDPoPTestServer(t, false)
+
+	key, _ := jwt.GenerateDPoPKey("EdDSA")
+	client := NewOauthProvider("client-1", "secret", nil)
+	client.DPoP = key
+
+	if _, err := client.ValidateAuthorizationCode(server.URL, "code-1", nil); !errors.Is(err, ErrOauthRequest) {
+		t.Errorf("expected ErrOauthRequest, got %v", err)
+	}
+	if len(proofs()) != 2 {
+		t.Errorf("expected a single retry, got %d requests", len(proofs()))
+	}
+}
+
+func TestDecodeTokenResponse_Empty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(nil)
+	}))
+	defer server.Close()
+
+	client := NewOauthProvider("client-1", "secret", nil)
+	if _, err := client.ValidateAuthorizationCode(server.URL, "code-1", nil); !errors.Is(err, ErrResponseEmpty) {
+		t.Errorf("expected ErrResponseEmpty, got %v", err)
+	}
+}
@@ -3,10 +3,10 @@ package providers
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"time"
 
 	"github.com/loggdme/strivia/jwt"
 	"github.com/loggdme/strivia/oauth"
+	"github.com/loggdme/strivia/oidc"
 )
 
 // AppleJWKS fetches the Apple JWKS.
@@ -18,75 +18,46 @@ func AppleJWKS(customEndpoint *string) (*jwt.JWKS, error) {
 	return jwt.FetchJWKS("https://appleid.apple.com/auth/keys")
 }
 
-// AppleUserFromIdTokenWithValidation extracts user information from a Apple ID token.
-// It does the same as AppleUserFromIdToken but also verifies the token signature
-// with the Google JWKS. Use this method when obtaining ID tokens from users.
-func AppleUserFromIdTokenWithValidation(jwks *jwt.JWKS, idToken string, nonce string, audience *string) (*oauth.OAuth2User, error) {
-	parsed, err := jwt.UnsecureDecodeToken[_AppleIdTokenClaims](idToken)
-	if err != nil {
-		return nil, err
-	}
-
-	// General validation
-	algorithm := parsed.Header["alg"].(string)
-	if algorithm != "RS256" {
-		return nil, jwt.ErrTokenInvalidAlgorithm
-	}
-
-	kid, ok := parsed.Header["kid"].(string)
-	if !ok {
-		return nil, oauth.ErrKidNotFound
-	}
-
-	if parsed.Claims.Issuer != "https://appleid.apple.com" {
-		return nil, jwt.ErrIssuerMismatch
-	}
-
-	if audience != nil && (len(parsed.Claims.Audience) != 1 || parsed.Claims.Audience[0] != *audience) {
-		return nil, jwt.ErrAudienceMismatch
+// AppleJWKSCache creates a self-refreshing cache for the Apple JWKS which
+// picks up rotated keys automatically. Call Start on the returned cache to refresh
+// the keys in the background.
+func AppleJWKSCache(customEndpoint *string) *jwt.JWKSCache {
+	if customEndpoint != nil {
+		return jwt.NewJWKSCache(*customEndpoint)
 	}
 
-	if time.Now().After(parsed.Claims.ExpiresAt.Time) {
-		return nil, jwt.ErrTokenExpired
-	}
+	return jwt.NewJWKSCache("https://appleid.apple.com/auth/keys")
+}
 
-	if parsed.Claims.Email == "" || !parsed.Claims.EmailVerified {
-		return nil, oauth.ErrNoVerifiedEmail
-	}
+// AppleUserFromVerifiedIdToken extracts user information from a Apple ID token.
+// It verifies the token with an [oidc.Verifier]: the signature with the Apple JWKS, the
+// issuer, that the token was issued to clientID and the nonce, which Apple returns as the
+// hex encoded SHA-256 hash of the nonce sent with the authentication request. Use this method
+// when obtaining ID tokens from users. The jwks can either be a static *jwt.JWKS or a
+// *jwt.JWKSCache.
+//
+// Besides the errors of [jwt.VerifyTokenWithJWKS], failures wrap [oauth.ErrKidNotFound],
+// [oauth.ErrInvalidPublicKey], [oauth.ErrVerificationFailed] and [oauth.ErrInvalidNonce] as
+// before.
+func AppleUserFromVerifiedIdToken(jwks jwt.JWKSource, idToken string, nonce string, clientID string) (*oauth.OAuth2User, error) {
+	verifier := oidc.NewVerifier("https://appleid.apple.com", clientID, jwks)
 
 	hashedNonce := sha256.Sum256([]byte(nonce))
 	hashedNonceString := hex.EncodeToString(hashedNonce[:])
-	if hashedNonceString != parsed.Claims.Nonce {
-		return nil, oauth.ErrInvalidNonce
-	}
-
-	// Get public key for signing check
-	jwk, err := jwks.FindKeyByKid(kid)
-	if err != nil {
-		return nil, oauth.ErrKidNotFound
-	}
 
-	pubKey, err := jwk.ToRSAPublicKey()
-	if err != nil {
-		return nil, oauth.ErrInvalidPublicKey
-	}
+	return verifyIdTokenUser(verifier, idToken, hashedNonceString)
+}
 
-	// Verify signature
-	tokenPayload := parsed.RawParts[0] + "." + parsed.RawParts[1]
-	if err := jwt.SigningMethodRS256.VerifyRSA(tokenPayload, parsed.Signature, pubKey); err != nil {
-		return nil, oauth.ErrVerificationFailed
+// AppleUserFromIdTokenWithValidation extracts user information from a Apple ID token and
+// verifies it like [AppleUserFromVerifiedIdToken].
+//
+// Deprecated: Use [AppleUserFromVerifiedIdToken]. The audience is now required, a nil
+// audience fails with [jwt.ErrAudienceIsRequired] instead of accepting tokens issued to any
+// client.
+func AppleUserFromIdTokenWithValidation(jwks jwt.JWKSource, idToken string, nonce string, audience *string) (*oauth.OAuth2User, error) {
+	if audience == nil {
+		return nil, jwt.ErrAudienceIsRequired
 	}
 
-	// Return user information
-	return &oauth.OAuth2User{
-		ID:    parsed.Claims.Subject,
-		Email: parsed.Claims.Email,
-	}, nil
-}
-
-type _AppleIdTokenClaims struct {
-	Nonce         string `json:"nonce"`
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
-	jwt.RegisteredClaims
+	return AppleUserFromVerifiedIdToken(jwks, idToken, nonce, *audience)
 }
@@ -1,10 +1,9 @@
 package providers
 
 import (
-	"time"
-
 	"github.com/loggdme/strivia/jwt"
 	"github.com/loggdme/strivia/oauth"
+	"github.com/loggdme/strivia/oidc"
 )
 
 type GoogleProvider struct {
@@ -43,7 +42,7 @@ func (p *GoogleProvider) ValidateAuthorizationCode(code string, codeVerifier str
 // Read more about it here: https://developers.google.com/identity/openid-connect/openid-connect#an-id-tokens-payload
 // Use this method when obtaining ID tokens from trusted sources.
 func GoogleUserFromIdToken(idToken string) (*oauth.OAuth2User, error) {
-	claims, err := oauth.DecodeIdToken[_GoogleIdTokenClaims](idToken)
+	claims, err := oauth.DecodeIdToken[oidc.IDTokenClaims](idToken)
 	if err != nil {
 		return nil, err
 	}
@@ -67,68 +66,44 @@ func GoogleJWKS(customEndpoint *string) (*jwt.JWKS, error) {
 	return jwt.FetchJWKS("https://www.googleapis.com/oauth2/v3/certs")
 }
 
-// GoogleUserFromIdTokenWithValidation extracts user information from a Google ID token.
-// It does the same as GoogleUserFromIdToken but also verifies the token signature
-// with the Google JWKS. Use this method when obtaining ID tokens from users.
-func GoogleUserFromIdTokenWithValidation(jwks *jwt.JWKS, idToken string, audience *string) (*oauth.OAuth2User, error) {
-	parsed, err := jwt.UnsecureDecodeToken[_GoogleIdTokenClaims](idToken)
-	if err != nil {
-		return nil, err
-	}
-
-	// General validation
-	algorithm := parsed.Header["alg"].(string)
-	if algorithm != "RS256" {
-		return nil, jwt.ErrTokenInvalidAlgorithm
-	}
-
-	kid, ok := parsed.Header["kid"].(string)
-	if !ok {
-		return nil, oauth.ErrKidNotFound
-	}
-
-	if parsed.Claims.Issuer != "https://accounts.google.com" {
-		return nil, jwt.ErrIssuerMismatch
-	}
-
-	if audience != nil && (len(parsed.Claims.Audience) != 1 || parsed.Claims.Audience[0] != *audience) {
-		return nil, jwt.ErrAudienceMismatch
-	}
-
-	if time.Now().After(parsed.Claims.ExpiresAt.Time) {
-		return nil, jwt.ErrTokenExpired
-	}
-
-	if parsed.Claims.Email == "" || !parsed.Claims.EmailVerified {
-		return nil, oauth.ErrNoVerifiedEmail
+// GoogleJWKSCache creates a self-refreshing cache for the Google JWKS which
+// picks up rotated keys automatically. Call Start on the returned cache to refresh
+// the keys in the background.
+func GoogleJWKSCache(customEndpoint *string) *jwt.JWKSCache {
+	if customEndpoint != nil {
+		return jwt.NewJWKSCache(*customEndpoint)
 	}
 
-	// Get public key for signing check
-	jwk, err := jwks.FindKeyByKid(kid)
-	if err != nil {
-		return nil, oauth.ErrKidNotFound
-	}
+	return jwt.NewJWKSCache("https://www.googleapis.com/oauth2/v3/certs")
+}
 
-	pubKey, err := jwk.ToRSAPublicKey()
-	if err != nil {
-		return nil, oauth.ErrInvalidPublicKey
-	}
+// GoogleUserFromVerifiedIdToken extracts user information from a Google ID token.
+// It does the same as GoogleUserFromIdToken but also verifies the token with an [oidc.Verifier]:
+// the signature with the Google JWKS, the issuer (`https://accounts.google.com` or
+// `accounts.google.com`), that the token was issued to clientID and, if nonce is not empty,
+// the nonce of the authentication request. Use this method when obtaining ID tokens from
+// users. The jwks can either be a static *jwt.JWKS or a *jwt.JWKSCache.
+//
+// Besides the errors of [jwt.VerifyTokenWithJWKS], failures wrap [oauth.ErrKidNotFound],
+// [oauth.ErrInvalidPublicKey], [oauth.ErrVerificationFailed] and [oauth.ErrInvalidNonce] as
+// before.
+func GoogleUserFromVerifiedIdToken(jwks jwt.JWKSource, idToken string, nonce string, clientID string) (*oauth.OAuth2User, error) {
+	verifier := oidc.NewVerifier("https://accounts.google.com", clientID, jwks)
+	verifier.IssuerAliases = []string{"accounts.google.com"}
+
+	return verifyIdTokenUser(verifier, idToken, nonce)
+}
 
-	// Verify signature
-	tokenPayload := parsed.RawParts[0] + "." + parsed.RawParts[1]
-	if err := jwt.SigningMethodRS256.VerifyRSA(tokenPayload, parsed.Signature, pubKey); err != nil {
-		return nil, oauth.ErrVerificationFailed
+// GoogleUserFromIdTokenWithValidation extracts user information from a Google ID token and
+// verifies it like [GoogleUserFromVerifiedIdToken] without a nonce.
+//
+// Deprecated: Use [GoogleUserFromVerifiedIdToken], which also checks the nonce. The audience
+// is now required, a nil audience fails with [jwt.ErrAudienceIsRequired] instead of
+// accepting tokens issued to any client.
+func GoogleUserFromIdTokenWithValidation(jwks jwt.JWKSource, idToken string, audience *string) (*oauth.OAuth2User, error) {
+	if audience == nil {
+		return nil, jwt.ErrAudienceIsRequired
 	}
 
-	// Return user information
-	return &oauth.OAuth2User{
-		ID:    parsed.Claims.Subject,
-		Email: parsed.Claims.Email,
-	}, nil
-}
-
-type _GoogleIdTokenClaims struct {
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
-	jwt.RegisteredClaims
+	return GoogleUserFromVerifiedIdToken(jwks, idToken, "", *audience)
 }
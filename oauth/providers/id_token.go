@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/loggdme/strivia/jwt"
+	"github.com/loggdme/strivia/oauth"
+	"github.com/loggdme/strivia/oidc"
+)
+
+// verifyIdTokenUser verifies the ID token with verifier and returns the user it identifies,
+// which must have a verified email address.
+func verifyIdTokenUser(verifier *oidc.Verifier, idToken string, nonce string) (*oauth.OAuth2User, error) {
+	parsed, err := verifier.Verify(idToken, &oidc.VerifyOptions{Nonce: nonce})
+	if err != nil {
+		return nil, idTokenError(err)
+	}
+
+	if parsed.Claims.Email == "" || !parsed.Claims.EmailVerified {
+		return nil, oauth.ErrNoVerifiedEmail
+	}
+
+	// Return user information
+	return &oauth.OAuth2User{
+		ID:    parsed.Claims.Subject,
+		Email: parsed.Claims.Email,
+	}, nil
+}
+
+// idTokenError wraps verification errors in the oauth errors the providers returned before
+// they verified ID tokens with an [oidc.Verifier], so both can be matched with errors.Is.
+func idTokenError(err error) error {
+	var validationErr *jwt.ValidationError
+
+	switch {
+	case errors.Is(err, jwt.ErrKeyNotFound), errors.Is(err, jwt.ErrTokenMissingKid):
+		return fmt.Errorf("%w: %w", oauth.ErrKidNotFound, err)
+	case errors.Is(err, jwt.ErrInvalidKey), errors.Is(err, jwt.ErrInvalidKeyType):
+		return fmt.Errorf("%w: %w", oauth.ErrInvalidPublicKey, err)
+	case errors.Is(err, jwt.ErrRSAVerification), errors.Is(err, jwt.ErrECDSAVerification), errors.Is(err, jwt.ErrEd25519Verification):
+		return fmt.Errorf("%w: %w", oauth.ErrVerificationFailed, err)
+	case errors.As(err, &validationErr) && validationErr.HasClaim("nonce"):
+		return fmt.Errorf("%w: %w", oauth.ErrInvalidNonce, err)
+	}
+
+	return err
+}
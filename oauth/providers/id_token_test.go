@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+	"github.com/loggdme/strivia/oauth"
+)
+
+func signTestIdToken(t *testing.T, key *rsa.PrivateKey, issuer string) string {
+	t.Helper()
+
+	now := time.Now()
+	h, _ := json.Marshal(map[string]any{"alg": "RS256", "typ": "JWT", "kid": "key-1"})
+	c, _ := json.Marshal(map[string]any{
+		"iss":            issuer,
+		"sub":            "user-1",
+		"aud":            "client-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "user@example.com",
+		"email_verified": true,
+	})
+	signingString := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	sig, err := jwt.SigningMethodRS256.SignRSA(signingString, key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestUserFromVerifiedIdToken_Errors(t *testing.T) {
+	key, err := jwt.GenerateRSAKey(2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, _ := jwt.GenerateRSAKey(2048)
+
+	jwk, _ := jwt.NewRSAJWK(&key.PublicKey, "key-1", "RS256")
+	otherJWK, _ := jwt.NewRSAJWK(&otherKey.PublicKey, "key-1", "RS256")
+	malformedJWK := *jwk
+	malformedJWK.N = "!!"
+
+	providers := map[string]struct {
+		issuer string
+		verify func(jwks jwt.JWKSource, idToken string) (*oauth.OAuth2User, error)
+	}{
+		"google": {issuer: "https://accounts.google.com", verify: func(jwks jwt.JWKSource, idToken string) (*oauth.OAuth2User, error) {
+			return GoogleUserFromVerifiedIdToken(jwks, idToken, "", "client-1")
+		}},
+		"apple": {issuer: "https://appleid.apple.com", verify: func(jwks jwt.JWKSource, idToken string) (*oauth.OAuth2User, error) {
+			return AppleUserFromVerifiedIdToken(jwks, idToken, "", "client-1")
+		}},
+	}
+
+	tests := map[string]struct {
+		jwks *jwt.JWKS
+		err  error
+	}{
+		"malformed key": {jwks: &jwt.JWKS{Keys: []jwt.JWK{malformedJWK}}, err: oauth.ErrInvalidPublicKey},
+		"unknown kid":   {jwks: &jwt.JWKS{}, err: oauth.ErrKidNotFound},
+		"wrong key":     {jwks: &jwt.JWKS{Keys: []jwt.JWK{*otherJWK}}, err: oauth.ErrVerificationFailed},
+	}
+
+	for provider, p := range providers {
+		idToken := signTestIdToken(t, key, p.issuer)
+
+		for name, tt := range tests {
+			t.Run(provider+" "+name, func(t *testing.T) {
+				if _, err := p.verify(tt.jwks, idToken); !errors.Is(err, tt.err) {
+					t.Errorf("expected %v, got %v", tt.err, err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+	strivia_random "github.com/loggdme/strivia/random"
+)
+
+var (
+	ErrTokenExchangeInvalidRequest = errors.New("oauth: token exchange request is invalid")
+	ErrTokenExchangeInvalidGrant   = errors.New("oauth: subject or actor token is invalid")
+	ErrTokenExchangeInvalidTarget  = errors.New("oauth: requested audience or resource is not allowed")
+	ErrTokenExchangeInvalidScope   = errors.New("oauth: requested scope exceeds the scope of the subject token")
+	ErrTokenExchangeNotAllowed     = errors.New("oauth: token exchange is not allowed for the client")
+)
+
+// GrantTypeTokenExchange is the grant type of token exchange requests, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.1.
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token type identifiers of the subject, actor, requested and issued tokens, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-3.
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeJWT          = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeRequest is a token exchange request, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.1. SubjectToken is the token of the
+// party on whose behalf the request is made. With an ActorToken the issued token delegates to
+// the actor, without one the client impersonates the subject.
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+	Audience           []string
+	Resource           []string
+	Scopes             []string
+}
+
+// TokenExchangeResponse is the response to a successful token exchange, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.2.1.
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+}
+
+// ExchangeToken exchanges the subject token of request for a new token at the token endpoint
+// of the authorization server, e.g. a token with a narrower audience and scope for a
+// downstream service. The client authenticates with its credentials.
+func (p *OAuth2Client) ExchangeToken(endpoint string, request *TokenExchangeRequest) (*TokenExchangeResponse, error) {
+	body, err := request.values()
+	if err != nil {
+		return nil, err
+	}
+
+	tokensMap, err := p.sendTokenRequest(endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TokenExchangeResponse{}
+
+	if accessToken, ok := (*tokensMap)["access_token"].(string); ok {
+		response.AccessToken = accessToken
+	}
+
+	if issuedTokenType, ok := (*tokensMap)["issued_token_type"].(string); ok {
+		response.IssuedTokenType = issuedTokenType
+	}
+
+	if tokenType, ok := (*tokensMap)["token_type"].(string); ok {
+		response.TokenType = tokenType
+	}
+
+	if expiresIn, ok := (*tokensMap)["expires_in"].(float64); ok {
+		response.ExpiresIn = int64(expiresIn)
+	}
+
+	if scope, ok := (*tokensMap)["scope"].(string); ok {
+		response.Scope = scope
+	}
+
+	if refreshToken, ok := (*tokensMap)["refresh_token"].(string); ok {
+		response.RefreshToken = refreshToken
+	}
+
+	if response.AccessToken == "" {
+		return nil, ErrTokenResponse
+	}
+
+	return response, nil
+}
+
+// values encodes the request as form parameters. The subject token and its type are required,
+// as is the type of an actor token.
+func (r *TokenExchangeRequest) values() (url.Values, error) {
+	if r.SubjectToken == "" || r.SubjectTokenType == "" || (r.ActorToken != "" && r.ActorTokenType == "") {
+		return nil, ErrTokenExchangeInvalidRequest
+	}
+
+	body := url.Values{}
+
+	body.Set("grant_type", GrantTypeTokenExchange)
+	body.Set("subject_token", r.SubjectToken)
+	body.Set("subject_token_type", r.SubjectTokenType)
+	if r.ActorToken != "" {
+		body.Set("actor_token", r.ActorToken)
+		body.Set("actor_token_type", r.ActorTokenType)
+	}
+	if r.RequestedTokenType != "" {
+		body.Set("requested_token_type", r.RequestedTokenType)
+	}
+	for _, audience := range r.Audience {
+		body.Add("audience", audience)
+	}
+	for _, resource := range r.Resource {
+		body.Add("resource", resource)
+	}
+	if len(r.Scopes) > 0 {
+		body.Set("scope", strings.Join(r.Scopes, " "))
+	}
+
+	return body, nil
+}
+
+// parseTokenExchangeRequest reads a token exchange request from the form parameters.
+func parseTokenExchangeRequest(form url.Values) *TokenExchangeRequest {
+	return &TokenExchangeRequest{
+		SubjectToken:       form.Get("subject_token"),
+		SubjectTokenType:   form.Get("subject_token_type"),
+		ActorToken:         form.Get("actor_token"),
+		ActorTokenType:     form.Get("actor_token_type"),
+		RequestedTokenType: form.Get("requested_token_type"),
+		Audience:           form["audience"],
+		Resource:           form["resource"],
+		Scopes:             strings.Fields(form.Get("scope")),
+	}
+}
+
+// TokenExchange describes a token exchange which was verified by a [TokenExchanger] and is
+// about to be issued. Subject and Actor are the claims of the verified subject and actor
+// tokens, Actor is nil for impersonation.
+type TokenExchange struct {
+	ClientID string
+	Subject  *jwt.AccessTokenClaims
+	Actor    *jwt.AccessTokenClaims
+	Audience []string
+	Scopes   []string
+}
+
+// defaultTokenExchangeTTL is the lifetime of tokens issued by a TokenExchanger without TTL.
+const defaultTokenExchangeTTL = 5 * time.Minute
+
+// TokenExchanger is the server side of token exchange for JWT access tokens. Subject and actor
+// tokens are verified as access tokens with KeySet and Expected, see
+// [jwt.VerifyAccessTokenWithKeySet], the issued access tokens are signed with the
+// active key of KeySet. The issued token keeps the subject, is restricted to the requested
+// audience and to the requested scopes, which have to be granted by the subject token, and
+// never outlives the subject token. With an actor token, the actor is recorded in the `act`
+// claim on top of any actors of the subject token, so the whole delegation chain stays visible.
+type TokenExchanger struct {
+	KeySet   *jwt.KeySet
+	Expected *jwt.ExpectedClaims
+	Issuer   string
+	TTL      time.Duration
+
+	// Policy decides whether the client may perform the exchange, e.g. which audiences it may
+	// request tokens for. It is required, a nil Policy denies every exchange. Denials should
+	// wrap ErrTokenExchangeNotAllowed, other errors are treated as internal failures.
+	Policy func(exchange *TokenExchange) error
+
+	now func() time.Time
+}
+
+// NewTokenExchanger creates and returns a new TokenExchanger which issues access tokens as
+// issuer with the keys of ks for the exchanges policy allows. Issued tokens are valid for at
+// most five minutes unless TTL is changed, which is also the lifetime used for a zero TTL.
+func NewTokenExchanger(ks *jwt.KeySet, issuer string, expected *jwt.ExpectedClaims, policy func(exchange *TokenExchange) error) *TokenExchanger {
+	return &TokenExchanger{KeySet: ks, Expected: expected, Issuer: issuer, TTL: defaultTokenExchangeTTL, Policy: policy, now: time.Now}
+}
+
+// Exchange verifies the subject and actor tokens of request for the authenticated client
+// clientID and issues a new access token. Its errors are one of the ErrTokenExchange errors
+// or an error returned by Policy. Without a Policy it fails with ErrTokenExchangeNotAllowed.
+func (e *TokenExchanger) Exchange(clientID string, request *TokenExchangeRequest) (*TokenExchangeResponse, error) {
+	if request.SubjectToken == "" || !isJWTTokenType(request.SubjectTokenType) {
+		return nil, ErrTokenExchangeInvalidRequest
+	}
+
+	if request.RequestedTokenType != "" && !isJWTTokenType(request.RequestedTokenType) {
+		return nil, ErrTokenExchangeInvalidRequest
+	}
+
+	subject, err := e.verify(request.SubjectToken)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange := &TokenExchange{ClientID: clientID, Subject: subject, Scopes: request.Scopes}
+
+	if request.ActorToken != "" {
+		if !isJWTTokenType(request.ActorTokenType) {
+			return nil, ErrTokenExchangeInvalidRequest
+		}
+
+		if exchange.Actor, err = e.verify(request.ActorToken); err != nil {
+			return nil, err
+		}
+
+		if mayAct := subject.MayAct; mayAct != nil &&
+			(mayAct.Subject != exchange.Actor.Subject || (mayAct.Issuer != "" && mayAct.Issuer != exchange.Actor.Issuer)) {
+			return nil, ErrTokenExchangeNotAllowed
+		}
+	}
+
+	exchange.Audience = append(slices.Clone(request.Audience), request.Resource...)
+	if len(exchange.Audience) == 0 {
+		return nil, ErrTokenExchangeInvalidTarget
+	}
+
+	if len(exchange.Scopes) == 0 {
+		exchange.Scopes = subject.Scopes()
+	}
+	for _, scope := range exchange.Scopes {
+		if !subject.HasScope(scope) {
+			return nil, ErrTokenExchangeInvalidScope
+		}
+	}
+
+	if e.Policy == nil {
+		return nil, ErrTokenExchangeNotAllowed
+	}
+	if err := e.Policy(exchange); err != nil {
+		return nil, err
+	}
+
+	return e.issue(exchange)
+}
+
+// verify verifies a subject or actor token, which has to be a JWT access token.
+func (e *TokenExchanger) verify(token string) (*jwt.AccessTokenClaims, error) {
+	parsed, err := jwt.VerifyAccessTokenWithKeySet[jwt.AccessTokenClaims](token, e.KeySet, e.Expected)
+	if err != nil {
+		return nil, errors.Join(ErrTokenExchangeInvalidGrant, err)
+	}
+
+	return parsed.Claims, nil
+}
+
+// issue mints the access token of a verified exchange.
+func (e *TokenExchanger) issue(exchange *TokenExchange) (*TokenExchangeResponse, error) {
+	now := time.Now()
+	if e.now != nil {
+		now = e.now()
+	}
+
+	ttl := e.TTL
+	if ttl <= 0 {
+		ttl = defaultTokenExchangeTTL
+	}
+
+	expiresAt := now.Add(ttl)
+	if exp := exchange.Subject.ExpiresAt; exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
+	}
+
+	subject := exchange.Subject
+	actor := subject.Actor
+	if exchange.Actor != nil {
+		actor = &jwt.Actor{Subject: exchange.Actor.Subject, Issuer: exchange.Actor.Issuer, Actor: subject.Actor}
+	}
+
+	claims := &jwt.AccessTokenClaims{
+		ClientID: exchange.ClientID,
+		Scope:    strings.Join(exchange.Scopes, " "),
+		AuthTime: subject.AuthTime,
+		ACR:      subject.ACR,
+		AMR:      subject.AMR,
+		Actor:    actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strivia_random.SecureRandomBase32String(20),
+			Issuer:    e.Issuer,
+			Subject:   subject.Subject,
+			Audience:  exchange.Audience,
+			ExpiresAt: &jwt.NumericDate{Time: expiresAt},
+			NotBefore: &jwt.NumericDate{Time: now},
+			IssuedAt:  &jwt.NumericDate{Time: now},
+		},
+	}
+
+	accessToken, err := jwt.NewAccessToken(claims).SignedStringWithKeySet(e.KeySet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenExchangeResponse{
+		AccessToken:     accessToken,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(expiresAt.Sub(now).Seconds()),
+		Scope:           claims.Scope,
+	}, nil
+}
+
+// isJWTTokenType reports whether tokens of the type are JWTs the TokenExchanger can process.
+func isJWTTokenType(tokenType string) bool {
+	return tokenType == TokenTypeAccessToken || tokenType == TokenTypeJWT
+}
+
+// TokenExchangeHandler serves token exchange requests at a token endpoint, see
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2. Only clients accepted by
+// Authenticate, e.g. [BasicClientAuthenticator], may exchange tokens, the client id is taken
+// from their HTTP Basic credentials. Errors of the exchange are answered with the error codes
+// of RFC 8693, internal failures, e.g. of signing or of the Policy, with 500 `server_error`.
+//
+// Issued tokens are bearer tokens: a `cnf` claim of the subject token is not carried over, as
+// the client did not prove possession of the bound key. Sender-constrained subject tokens
+// therefore lose their binding when exchanged, which the Policy has to take into account.
+type TokenExchangeHandler struct {
+	Exchanger    *TokenExchanger
+	Authenticate func(r *http.Request) bool
+}
+
+// NewTokenExchangeHandler creates and returns a new TokenExchangeHandler which exchanges tokens
+// with exchanger and authenticates clients with authenticate.
+func NewTokenExchangeHandler(exchanger *TokenExchanger, authenticate func(r *http.Request) bool) *TokenExchangeHandler {
+	return &TokenExchangeHandler{Exchanger: exchanger, Authenticate: authenticate}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *TokenExchangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID, _, _ := r.BasicAuth()
+	if h.Authenticate == nil || !h.Authenticate(r) || clientID == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		writeNoStoreJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeNoStoreJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != GrantTypeTokenExchange {
+		writeNoStoreJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	response, err := h.Exchanger.Exchange(clientID, parseTokenExchangeRequest(r.PostForm))
+	if err != nil {
+		code := tokenExchangeErrorCode(err)
+
+		status := http.StatusBadRequest
+		if code == "server_error" {
+			status = http.StatusInternalServerError
+		}
+
+		writeNoStoreJSON(w, status, map[string]string{"error": code})
+		return
+	}
+
+	writeNoStoreJSON(w, http.StatusOK, response)
+}
+
+// tokenExchangeErrorCode maps an error of [TokenExchanger.Exchange] to the error code of the
+// response, see https://datatracker.ietf.org/doc/html/rfc8693#section-2.2.2.
+func tokenExchangeErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExchangeInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, ErrTokenExchangeInvalidGrant):
+		return "invalid_grant"
+	case errors.Is(err, ErrTokenExchangeInvalidTarget):
+		return "invalid_target"
+	case errors.Is(err, ErrTokenExchangeInvalidScope):
+		return "invalid_scope"
+	case errors.Is(err, ErrTokenExchangeNotAllowed):
+		return "unauthorized_client"
+	}
+
+	return "server_error"
+}
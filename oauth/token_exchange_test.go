@@ -0,0 +1,295 @@
+package oauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+const tokenExchangeTestIssuer = "https://as.example.com"
+
+type tokenExchangeTest struct {
+	keys      *jwt.KeySet
+	exchanger *TokenExchanger
+	handler   http.Handler
+}
+
+func newTokenExchangeTest(t *testing.T, policy func(exchange *TokenExchange) error) *tokenExchangeTest {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	key := jwt.PrivateKey(privateKey)
+
+	keys, err := jwt.NewKeySet(jwt.SigningKey{Kid: "key-1", PrivateKey: &key})
+	if err != nil {
+		t.Fatalf("unexpected error creating key set: %v", err)
+	}
+
+	exchanger := NewTokenExchanger(keys, tokenExchangeTestIssuer, &jwt.ExpectedClaims{Issuer: tokenExchangeTestIssuer, Audience: []string{"gateway"}}, policy)
+	handler := NewTokenExchangeHandler(exchanger, BasicClientAuthenticator(map[string]string{"gateway": "secret"}))
+
+	return &tokenExchangeTest{keys: keys, exchanger: exchanger, handler: handler}
+}
+
+// accessToken issues an access token for the gateway with the given claims on top of valid
+// defaults.
+func (e *tokenExchangeTest) accessToken(t *testing.T, subject string, scope string, modify func(claims *jwt.AccessTokenClaims)) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := &jwt.AccessTokenClaims{
+		ClientID: "web",
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        subject + "-token",
+			Issuer:    tokenExchangeTestIssuer,
+			Subject:   subject,
+			Audience:  jwt.Audience{"gateway"},
+			ExpiresAt: &jwt.NumericDate{Time: now.Add(time.Hour)},
+			NotBefore: &jwt.NumericDate{Time: now},
+			IssuedAt:  &jwt.NumericDate{Time: now},
+		},
+	}
+	if modify != nil {
+		modify(claims)
+	}
+
+	signed, err := jwt.NewAccessToken(claims).SignedStringWithKeySet(e.keys)
+	if err != nil {
+		t.Fatalf("unexpected error signing: %v", err)
+	}
+
+	return signed
+}
+
+// exchange posts a token exchange request to the handler and returns the status code, the
+// error code and, on success, the verified claims of the issued token.
+func (e *tokenExchangeTest) exchange(t *testing.T, form url.Values) (int, string, *jwt.AccessTokenClaims) {
+	t.Helper()
+
+	form.Set("grant_type", GrantTypeTokenExchange)
+	if !form.Has("subject_token_type") {
+		form.Set("subject_token_type", TokenTypeAccessToken)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("gateway", "secret")
+	rec := httptest.NewRecorder()
+	e.handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		var body map[string]string
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body["error"], nil
+	}
+
+	var response TokenExchangeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+
+	token, err := jwt.VerifyAccessTokenWithKeySet[jwt.AccessTokenClaims](response.AccessToken, e.keys, &jwt.ExpectedClaims{
+		Issuer:   tokenExchangeTestIssuer,
+		Audience: []string{"orders"},
+	})
+	if err != nil {
+		t.Fatalf("expected issued token to verify, got %v", err)
+	}
+
+	return rec.Code, "", token.Claims
+}
+
+func allowAllExchanges(*TokenExchange) error { return nil }
+
+func TestTokenExchangeHandler_Policy(t *testing.T) {
+	denied := fmt.Errorf("%w: client may not request tokens for orders", ErrTokenExchangeNotAllowed)
+
+	tests := map[string]struct {
+		policy func(exchange *TokenExchange) error
+		status int
+		code   string
+	}{
+		"nil policy denies": {policy: nil, status: http.StatusBadRequest, code: "unauthorized_client"},
+		"policy denies":     {policy: func(*TokenExchange) error { return denied }, status: http.StatusBadRequest, code: "unauthorized_client"},
+		"policy fails":      {policy: func(*TokenExchange) error { return errors.New("policy store unavailable") }, status: http.StatusInternalServerError, code: "server_error"},
+		"policy allows":     {policy: allowAllExchanges, status: http.StatusOK},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := newTokenExchangeTest(t, tt.policy)
+
+			status, code, _ := e.exchange(t, url.Values{
+				"subject_token": {e.accessToken(t, "user-1", "orders:read", nil)},
+				"audience":      {"orders"},
+			})
+			if status != tt.status || code != tt.code {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.code, status, code)
+			}
+		})
+	}
+
+	var seen *TokenExchange
+	e := newTokenExchangeTest(t, func(exchange *TokenExchange) error {
+		seen = exchange
+		return nil
+	})
+	e.exchange(t, url.Values{"subject_token": {e.accessToken(t, "user-1", "orders:read", nil)}, "audience": {"orders"}})
+	if seen == nil || seen.ClientID != "gateway" || seen.Subject.Subject != "user-1" || !slices.Equal(seen.Audience, []string{"orders"}) {
+		t.Errorf("expected policy to see the verified exchange, got %+v", seen)
+	}
+}
+
+func TestTokenExchangeHandler_ScopeNarrowing(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+	subjectToken := e.accessToken(t, "user-1", "orders:read orders:write profile", nil)
+
+	_, _, claims := e.exchange(t, url.Values{"subject_token": {subjectToken}, "audience": {"orders"}, "scope": {"orders:read"}})
+	if claims == nil || claims.Scope != "orders:read" || claims.Subject != "user-1" || claims.ClientID != "gateway" {
+		t.Errorf("expected token narrowed to orders:read for user-1, got %+v", claims)
+	}
+
+	_, _, claims = e.exchange(t, url.Values{"subject_token": {subjectToken}, "audience": {"orders"}})
+	if claims == nil || claims.Scope != "orders:read orders:write profile" {
+		t.Errorf("expected scope of the subject token without requested scope, got %+v", claims)
+	}
+
+	if status, code, _ := e.exchange(t, url.Values{"subject_token": {subjectToken}, "audience": {"orders"}, "scope": {"orders:read admin"}}); status != http.StatusBadRequest || code != "invalid_scope" {
+		t.Errorf("expected invalid_scope for broader scope, got %d %q", status, code)
+	}
+
+	if status, code, _ := e.exchange(t, url.Values{"subject_token": {subjectToken}}); code != "invalid_target" {
+		t.Errorf("expected invalid_target without audience, got %d %q", status, code)
+	}
+}
+
+func TestTokenExchangeHandler_RejectsNonAccessTokens(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+
+	// A token of the same issuer which is not typed as JWT access token, e.g. an ID token.
+	now := time.Now()
+	idToken, _ := jwt.NewToken(&jwt.AccessTokenClaims{
+		ClientID: "web",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "id-token",
+			Issuer:    tokenExchangeTestIssuer,
+			Subject:   "user-1",
+			Audience:  jwt.Audience{"gateway"},
+			ExpiresAt: &jwt.NumericDate{Time: now.Add(time.Hour)},
+			NotBefore: &jwt.NumericDate{Time: now},
+			IssuedAt:  &jwt.NumericDate{Time: now},
+		},
+	}).SignedStringWithKeySet(e.keys)
+
+	if status, code, _ := e.exchange(t, url.Values{"subject_token": {idToken}, "audience": {"orders"}}); code != "invalid_grant" {
+		t.Errorf("expected invalid_grant for a token without 'at+jwt' type, got %d %q", status, code)
+	}
+}
+
+func TestTokenExchangeHandler_MayAct(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+	subjectToken := e.accessToken(t, "user-1", "orders:read", func(claims *jwt.AccessTokenClaims) {
+		claims.MayAct = &jwt.Actor{Subject: "service-a", Issuer: tokenExchangeTestIssuer}
+	})
+
+	form := func(actor string) url.Values {
+		return url.Values{
+			"subject_token":    {subjectToken},
+			"actor_token":      {e.accessToken(t, actor, "", nil)},
+			"actor_token_type": {TokenTypeAccessToken},
+			"audience":         {"orders"},
+		}
+	}
+
+	_, _, claims := e.exchange(t, form("service-a"))
+	if claims == nil || claims.Actor == nil || claims.Actor.Subject != "service-a" {
+		t.Fatalf("expected service-a to act for user-1, got %+v", claims)
+	}
+
+	if status, code, _ := e.exchange(t, form("service-b")); code != "unauthorized_client" {
+		t.Errorf("expected unauthorized_client for an actor not in may_act, got %d %q", status, code)
+	}
+}
+
+func TestTokenExchangeHandler_NestedActorChain(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+
+	// user-1 delegated to service-a before, which now delegates to service-b.
+	subjectToken := e.accessToken(t, "user-1", "orders:read", func(claims *jwt.AccessTokenClaims) {
+		claims.Actor = &jwt.Actor{Subject: "service-a"}
+	})
+
+	_, _, claims := e.exchange(t, url.Values{
+		"subject_token":    {subjectToken},
+		"actor_token":      {e.accessToken(t, "service-b", "", nil)},
+		"actor_token_type": {TokenTypeAccessToken},
+		"audience":         {"orders"},
+	})
+	if claims == nil {
+		t.Fatal("expected exchange to succeed")
+	}
+
+	var chain []string
+	for _, actor := range claims.Actor.Chain() {
+		chain = append(chain, actor.Subject)
+	}
+	if claims.Subject != "user-1" || !slices.Equal(chain, []string{"service-b", "service-a"}) {
+		t.Errorf("expected user-1 with actor chain [service-b service-a], got %s %v", claims.Subject, chain)
+	}
+
+	// Impersonation keeps the actors of the subject token.
+	_, _, claims = e.exchange(t, url.Values{"subject_token": {subjectToken}, "audience": {"orders"}})
+	if claims == nil || claims.Actor == nil || claims.Actor.Subject != "service-a" || claims.Actor.Actor != nil {
+		t.Errorf("expected actor service-a to be kept, got %+v", claims)
+	}
+}
+
+func TestTokenExchangeHandler_SigningFailure(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+	subjectToken := e.accessToken(t, "user-1", "orders:read", nil)
+
+	// The retiring key still verifies the subject token, but no key is left to sign with.
+	if err := e.keys.SetState("key-1", jwt.KeyStateRetiring); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status, code, _ := e.exchange(t, url.Values{"subject_token": {subjectToken}, "audience": {"orders"}}); status != http.StatusInternalServerError || code != "server_error" {
+		t.Errorf("expected 500 server_error, got %d %q", status, code)
+	}
+}
+
+func TestTokenExchanger_ZeroTTL(t *testing.T) {
+	e := newTokenExchangeTest(t, allowAllExchanges)
+	exchanger := &TokenExchanger{KeySet: e.keys, Expected: e.exchanger.Expected, Issuer: tokenExchangeTestIssuer, Policy: allowAllExchanges}
+
+	response, err := exchanger.Exchange("gateway", &TokenExchangeRequest{
+		SubjectToken:     e.accessToken(t, "user-1", "orders:read", nil),
+		SubjectTokenType: TokenTypeAccessToken,
+		Audience:         []string{"orders"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.ExpiresIn <= 0 {
+		t.Errorf("expected the default lifetime for a zero TTL, got %d", response.ExpiresIn)
+	}
+
+	if _, err := jwt.VerifyAccessTokenWithKeySet[jwt.AccessTokenClaims](response.AccessToken, e.keys, &jwt.ExpectedClaims{Issuer: tokenExchangeTestIssuer, Audience: []string{"orders"}}); err != nil {
+		t.Errorf("expected issued token to verify, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+var (
+	ErrDiscoveryFetch          = errors.New("oidc: failed to fetch discovery document")
+	ErrDiscoveryIssuerMismatch = errors.New("oidc: discovery document issuer does not match the requested issuer")
+	ErrDiscoveryIncomplete     = errors.New("oidc: discovery document is missing required metadata")
+)
+
+// discoveryPath is appended to the issuer to locate the discovery document, see
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationRequest.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// ProviderMetadata is the discovery document of an OpenID provider, see
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata, including the
+// endpoints of the OAuth 2.0 extensions published in the same document.
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+	JWKSURI               string `json:"jwks_uri"`
+	RegistrationEndpoint  string `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint    string `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	ClaimsSupported                   []string `json:"claims_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+	DPoPSigningAlgValuesSupported     []string `json:"dpop_signing_alg_values_supported,omitempty"`
+}
+
+// SupportsCodeChallengeMethod reports whether the provider announces support for the PKCE
+// code challenge method, e.g. `S256`.
+func (m *ProviderMetadata) SupportsCodeChallengeMethod(method string) bool {
+	return slices.Contains(m.CodeChallengeMethodsSupported, method)
+}
+
+// SupportsScope reports whether the provider announces support for the scope. Providers are
+// not required to list their scopes, so false does not mean that the scope is rejected.
+func (m *ProviderMetadata) SupportsScope(scope string) bool {
+	return slices.Contains(m.ScopesSupported, scope)
+}
+
+// Verifier creates a Verifier for ID tokens the provider issued to clientID. The keys are
+// fetched lazily from the `jwks_uri` with a new [jwt.JWKSCache] and only the announced signing
+// algorithms are accepted, except for `none`. Every call creates a separate cache, so keep the
+// returned Verifier or use [Discovery.Verifier], which shares one cache across calls.
+func (m *ProviderMetadata) Verifier(clientID string) *Verifier {
+	return m.verifier(clientID, jwt.NewJWKSCache(m.JWKSURI))
+}
+
+// verifier creates a Verifier for clientID which verifies signatures with the keys of jwks.
+func (m *ProviderMetadata) verifier(clientID string, jwks jwt.JWKSource) *Verifier {
+	verifier := NewVerifier(m.Issuer, clientID, jwks)
+	for _, alg := range m.IDTokenSigningAlgValuesSupported {
+		if alg != "none" {
+			verifier.Algorithms = append(verifier.Algorithms, alg)
+		}
+	}
+
+	return verifier
+}
+
+// Discover fetches the discovery document of the issuer, e.g. `https://accounts.google.com`.
+// The `issuer` of the document has to be identical to the requested issuer, otherwise the
+// document could direct clients to keys of a different provider.
+func Discover(issuer string) (*ProviderMetadata, error) {
+	return fetchProviderMetadata(http.DefaultClient, issuer)
+}
+
+// Discovery is a concurrency-safe cache for the discovery document of an OpenID provider.
+// The document is fetched on first use and refreshed once it is older than TTL. If a refresh
+// fails, the previously fetched document is kept and served until a later refresh succeeds,
+// which is attempted at most once every RetryInterval. Callers are never blocked by the
+// refresh of an outdated document, they are served the outdated document meanwhile.
+//
+// The exported fields must not be modified after the cache is first used.
+type Discovery struct {
+	Issuer string
+	Http   *http.Client
+	TTL    time.Duration
+	// RetryInterval rate-limits fetches after a failed fetch.
+	RetryInterval time.Duration
+
+	mu        sync.Mutex
+	metadata  *ProviderMetadata
+	fetchedAt time.Time
+	failedAt  time.Time
+	fetchErr  error
+	jwks      *jwt.JWKSCache
+
+	fetchMu sync.Mutex
+
+	now func() time.Time
+}
+
+// NewDiscovery creates a new Discovery for the issuer which refreshes the document daily and
+// retries failed fetches after a minute.
+func NewDiscovery(issuer string) *Discovery {
+	return &Discovery{
+		Issuer:        issuer,
+		Http:          &http.Client{Timeout: 10 * time.Second},
+		TTL:           24 * time.Hour,
+		RetryInterval: time.Minute,
+		now:           time.Now,
+	}
+}
+
+// Metadata returns the cached discovery document, fetching it first if it is missing or older
+// than TTL. If a refresh of an outdated document fails or is already in progress, the
+// outdated document is returned instead of an error.
+func (d *Discovery) Metadata() (*ProviderMetadata, error) {
+	d.mu.Lock()
+	metadata, fetchedAt := d.metadata, d.fetchedAt
+	failedAt, fetchErr := d.failedAt, d.fetchErr
+	d.mu.Unlock()
+
+	now := d.now()
+	if metadata != nil && now.Sub(fetchedAt) < d.TTL {
+		return metadata, nil
+	}
+
+	// The last attempt failed recently, do not hammer the provider again.
+	if fetchErr != nil && now.Sub(failedAt) < d.RetryInterval {
+		if metadata != nil {
+			return metadata, nil
+		}
+		return nil, fetchErr
+	}
+
+	if metadata != nil {
+		if !d.fetchMu.TryLock() {
+			return metadata, nil
+		}
+	} else {
+		d.fetchMu.Lock()
+	}
+	defer d.fetchMu.Unlock()
+
+	// Another goroutine fetched the document or failed to while we were waiting for the lock.
+	d.mu.Lock()
+	if d.fetchedAt.After(fetchedAt) || d.failedAt.After(failedAt) {
+		metadata, err := d.metadata, d.fetchErr
+		d.mu.Unlock()
+
+		if metadata != nil {
+			return metadata, nil
+		}
+		return nil, err
+	}
+	d.mu.Unlock()
+
+	fetched, err := fetchProviderMetadata(d.Http, d.Issuer)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		d.failedAt, d.fetchErr = d.now(), err
+		if d.metadata != nil {
+			return d.metadata, nil
+		}
+		return nil, err
+	}
+
+	d.metadata = fetched
+	d.fetchedAt = d.now()
+	d.failedAt, d.fetchErr = time.Time{}, nil
+
+	return fetched, nil
+}
+
+// Verifier creates a Verifier for ID tokens the provider issued to clientID from the current
+// discovery document, see [ProviderMetadata.Verifier]. All verifiers share one [jwt.JWKSCache],
+// which is only replaced if the provider announces a different `jwks_uri`.
+func (d *Discovery) Verifier(clientID string) (*Verifier, error) {
+	metadata, err := d.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if d.jwks == nil || d.jwks.URL != metadata.JWKSURI {
+		d.jwks = jwt.NewJWKSCache(metadata.JWKSURI)
+	}
+	jwks := d.jwks
+	d.mu.Unlock()
+
+	return metadata.verifier(clientID, jwks), nil
+}
+
+// fetchProviderMetadata downloads, decodes and validates the discovery document of issuer.
+func fetchProviderMetadata(client *http.Client, issuer string) (*ProviderMetadata, error) {
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscoveryFetch, err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscoveryFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code: %d", ErrDiscoveryFetch, resp.StatusCode)
+	}
+
+	var metadata ProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscoveryFetch, err)
+	}
+
+	if metadata.Issuer != issuer {
+		return nil, ErrDiscoveryIssuerMismatch
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, ErrDiscoveryIncomplete
+	}
+
+	return &metadata, nil
+}
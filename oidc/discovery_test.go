@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDiscoveryServer(t *testing.T, modify func(metadata map[string]any)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+
+		metadata := map[string]any{
+			"issuer":                                server.URL,
+			"authorization_endpoint":                server.URL + "/authorize",
+			"token_endpoint":                        server.URL + "/token",
+			"jwks_uri":                              server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256", "ES256", "none"},
+			"scopes_supported":                      []string{"openid", "email"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		}
+		if modify != nil {
+			modify(metadata)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metadata)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestDiscover(t *testing.T) {
+	server, _ := newTestDiscoveryServer(t, nil)
+
+	metadata, err := Discover(server.URL)
+	if err != nil {
+		t.Fatalf("expected discovery to succeed, got %v", err)
+	}
+
+	if metadata.TokenEndpoint != server.URL+"/token" || metadata.JWKSURI != server.URL+"/jwks" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if !metadata.SupportsCodeChallengeMethod("S256") || metadata.SupportsCodeChallengeMethod("plain") {
+		t.Error("expected only S256 to be supported")
+	}
+	if !metadata.SupportsScope("email") {
+		t.Error("expected email scope to be supported")
+	}
+
+	verifier := metadata.Verifier("client-1")
+	if verifier.Issuer != server.URL || verifier.ClientID != "client-1" || len(verifier.Algorithms) != 2 {
+		t.Errorf("unexpected verifier %+v", verifier)
+	}
+}
+
+func TestDiscover_Rejects(t *testing.T) {
+	tests := map[string]struct {
+		modify func(metadata map[string]any)
+		err    error
+	}{
+		"issuer mismatch":  {modify: func(m map[string]any) { m["issuer"] = "https://evil.example.com" }, err: ErrDiscoveryIssuerMismatch},
+		"missing jwks_uri": {modify: func(m map[string]any) { delete(m, "jwks_uri") }, err: ErrDiscoveryIncomplete},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, _ := newTestDiscoveryServer(t, tt.modify)
+
+			if _, err := Discover(server.URL); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	server, _ := newTestDiscoveryServer(t, nil)
+	if _, err := Discover(server.URL + "/tenant"); !errors.Is(err, ErrDiscoveryFetch) {
+		t.Errorf("expected ErrDiscoveryFetch for a missing document, got %v", err)
+	}
+}
+
+func TestDiscovery_Caching(t *testing.T) {
+	server, requests := newTestDiscoveryServer(t, nil)
+
+	now := time.Now()
+	discovery := NewDiscovery(server.URL)
+	discovery.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := discovery.Metadata(); err != nil {
+			t.Fatalf("expected metadata, got %v", err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+
+	now = now.Add(25 * time.Hour)
+	discovery.Metadata()
+	if requests.Load() != 2 {
+		t.Errorf("expected outdated document to be refreshed, got %d requests", requests.Load())
+	}
+
+	// Refresh failures serve the outdated document.
+	server.Close()
+	now = now.Add(25 * time.Hour)
+	metadata, err := discovery.Metadata()
+	if err != nil || metadata.Issuer != server.URL {
+		t.Errorf("expected outdated document on refresh failure, got %v", err)
+	}
+}
+
+func TestDiscovery_RetryBackoff(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	discovery := NewDiscovery(server.URL)
+	discovery.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := discovery.Metadata(); !errors.Is(err, ErrDiscoveryFetch) {
+			t.Fatalf("expected ErrDiscoveryFetch, got %v", err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected failed fetch to be retried after RetryInterval only, got %d requests", requests.Load())
+	}
+
+	now = now.Add(discovery.RetryInterval)
+	discovery.Metadata()
+	if requests.Load() != 2 {
+		t.Errorf("expected retry after RetryInterval, got %d requests", requests.Load())
+	}
+}
+
+func TestDiscovery_RefreshDoesNotBlock(t *testing.T) {
+	server, requests := newTestDiscoveryServer(t, nil)
+
+	now := time.Now()
+	discovery := NewDiscovery(server.URL)
+	discovery.now = func() time.Time { return now }
+	discovery.Metadata()
+
+	// Simulate a slow refresh in progress.
+	discovery.fetchMu.Lock()
+	defer discovery.fetchMu.Unlock()
+
+	now = now.Add(25 * time.Hour)
+	metadata, err := discovery.Metadata()
+	if err != nil || metadata.Issuer != server.URL {
+		t.Errorf("expected outdated document during refresh, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected no second fetch while one is in progress, got %d requests", requests.Load())
+	}
+}
+
+func TestDiscovery_VerifierSharesJWKSCache(t *testing.T) {
+	server, _ := newTestDiscoveryServer(t, nil)
+	discovery := NewDiscovery(server.URL)
+
+	first, err := discovery.Verifier("client-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := discovery.Verifier("client-2")
+
+	if first.JWKS != second.JWKS {
+		t.Error("expected verifiers to share the JWKS cache")
+	}
+	if second.ClientID != "client-2" || len(second.Algorithms) != 2 {
+		t.Errorf("unexpected verifier %+v", second)
+	}
+}
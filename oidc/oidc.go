@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+var (
+	ErrNonceMismatch           = errors.New("oidc: 'nonce' claim does not match the expected nonce")
+	ErrAuthorizedPartyMismatch = errors.New("oidc: 'azp' claim does not match the client id")
+	ErrAuthenticationTooOld    = errors.New("oidc: authentication exceeds the maximum age")
+	ErrAccessTokenHashMismatch = errors.New("oidc: 'at_hash' claim does not match the access token")
+	ErrCodeHashMismatch        = errors.New("oidc: 'c_hash' claim does not match the authorization code")
+	ErrUnsupportedAlgorithm    = errors.New("oidc: token algorithm has no known hash function")
+)
+
+// IDTokenClaims are the claims of an ID token, see
+// https://openid.net/specs/openid-connect-core-1_0.html#IDToken, together with the standard
+// claims describing the user, see https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims.
+// Providers with additional claims can embed IDTokenClaims in their own claims type.
+type IDTokenClaims struct {
+	Nonce           string           `json:"nonce,omitempty"`
+	AuthTime        *jwt.NumericDate `json:"auth_time,omitempty"`
+	AuthorizedParty string           `json:"azp,omitempty"`
+	AccessTokenHash string           `json:"at_hash,omitempty"`
+	CodeHash        string           `json:"c_hash,omitempty"`
+	ACR             string           `json:"acr,omitempty"`
+	AMR             []string         `json:"amr,omitempty"`
+
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// Verifier verifies the ID tokens an OpenID provider issued to a client. The signature is
+// verified with the JWKS of the provider, usually a [jwt.JWKSCache], and only the algorithms
+// in Algorithms are accepted (only RS256 if empty). A Verifier is safe for concurrent use.
+type Verifier struct {
+	Issuer     string
+	ClientID   string
+	JWKS       jwt.JWKSource
+	Algorithms []string
+
+	// IssuerAliases are further `iss` values the provider uses next to Issuer, e.g. Google
+	// issues tokens for both `https://accounts.google.com` and `accounts.google.com`.
+	IssuerAliases []string
+
+	// Leeway tolerates clock skew between the provider and the client for all time based claims.
+	Leeway time.Duration
+	// Now returns the time the time based claims are validated against. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// VerifyOptions are the values of a single authentication request an ID token is checked
+// against. All of them are optional.
+type VerifyOptions struct {
+	// Nonce is the `nonce` sent with the authentication request, which the token has to carry.
+	Nonce string
+	// MaxAge is the `max_age` sent with the authentication request. The token then has to carry
+	// an `auth_time` claim which is at most MaxAge ago.
+	MaxAge time.Duration
+	// AccessToken is the access token issued along with the ID token. If the token carries an
+	// `at_hash` claim it has to match the access token.
+	AccessToken string
+	// Code is the authorization code issued along with the ID token in the hybrid flow. The
+	// token then has to carry a matching `c_hash` claim.
+	Code string
+	// Validators are additional rules the token has to satisfy, see [jwt.Validator].
+	Validators []jwt.Validator
+}
+
+// NewVerifier creates and returns a new Verifier for ID tokens which issuer issued to the
+// client clientID, signed with one of the keys of jwks.
+func NewVerifier(issuer string, clientID string, jwks jwt.JWKSource) *Verifier {
+	return &Verifier{Issuer: issuer, ClientID: clientID, JWKS: jwks}
+}
+
+// Verify verifies the ID token and returns it with its standard claims, see [VerifyIDToken].
+func (v *Verifier) Verify(idToken string, opts *VerifyOptions) (*jwt.Token[IDTokenClaims], error) {
+	return VerifyIDToken[IDTokenClaims](v, idToken, opts)
+}
+
+// VerifyIDToken verifies the signature of the ID token and validates it as described in
+// https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation: the `iss` claim has
+// to match the issuer, the `aud` claim has to contain the client id, which in turn has to be
+// the `azp` claim if it is present or the token has more than one audience, and the token has
+// to be issued (`iat`) and not expired. The `nbf` claim is optional. The checks of opts are
+// applied on top. JWT access tokens (`typ` header `at+jwt`) are never accepted as ID tokens.
+func VerifyIDToken[T jwt.Claims](v *Verifier, idToken string, opts *VerifyOptions) (*jwt.Token[T], error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+
+	validators := []jwt.Validator{rejectAccessTokens, v.validateAuthorizedParty}
+	if opts.Nonce != "" {
+		validators = append(validators, validateNonce(opts.Nonce))
+	}
+	if opts.MaxAge > 0 {
+		validators = append(validators, validateAuthTime(opts.MaxAge, v.Leeway))
+	}
+	if opts.AccessToken != "" {
+		validators = append(validators, validateHash("at_hash", opts.AccessToken, false, ErrAccessTokenHashMismatch))
+	}
+	if opts.Code != "" {
+		validators = append(validators, validateHash("c_hash", opts.Code, true, ErrCodeHashMismatch))
+	}
+
+	expected := &jwt.ExpectedClaims{
+		Issuer:            v.expectedIssuer(idToken),
+		Audience:          []string{v.ClientID},
+		Now:               v.Now,
+		ExpiresAtLeeway:   v.Leeway,
+		NotBeforeLeeway:   v.Leeway,
+		IssuedAtLeeway:    v.Leeway,
+		NotBeforeOptional: true,
+		Validators:        append(validators, opts.Validators...),
+	}
+
+	return jwt.VerifyTokenWithJWKS[T](idToken, v.JWKS, v.Algorithms, expected)
+}
+
+// expectedIssuer returns the alias of the issuer the ID token claims to be issued by, or
+// Issuer if the token names none of the aliases. The signature and the `iss` claim are still
+// verified against the returned issuer.
+func (v *Verifier) expectedIssuer(idToken string) string {
+	if len(v.IssuerAliases) == 0 {
+		return v.Issuer
+	}
+
+	token, err := jwt.UnsecureDecodeToken[jwt.RegisteredClaims](idToken)
+	if err != nil || !slices.Contains(v.IssuerAliases, token.Claims.Issuer) {
+		return v.Issuer
+	}
+
+	return token.Claims.Issuer
+}
+
+// TokenHash returns the value of the `at_hash` or `c_hash` claim for an access token or
+// authorization code of a token signed with alg: the left-most half of the hash of value,
+// base64url encoded. The hash function is the one of alg, e.g. SHA-256 for RS256 and ES256.
+func TokenHash(alg string, value string) (string, error) {
+	hash, err := hashForAlgorithm(alg)
+	if err != nil {
+		return "", err
+	}
+
+	h := hash.New()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
+
+// hashForAlgorithm returns the hash function of a JWS algorithm. EdDSA uses SHA-512, matching
+// the hash function of Ed25519.
+func hashForAlgorithm(alg string) (crypto.Hash, error) {
+	switch {
+	case alg == "EdDSA" || strings.HasSuffix(alg, "512"):
+		return crypto.SHA512, nil
+	case strings.HasSuffix(alg, "384"):
+		return crypto.SHA384, nil
+	case strings.HasSuffix(alg, "256"):
+		return crypto.SHA256, nil
+	}
+
+	return 0, ErrUnsupportedAlgorithm
+}
+
+// rejectAccessTokens rejects tokens with the `at+jwt` type, so access tokens signed with the
+// same keys cannot be used as ID tokens, see https://datatracker.ietf.org/doc/html/rfc9068#section-2.1.
+func rejectAccessTokens(ctx *jwt.ValidationContext) error {
+	typ, _ := ctx.Header["typ"].(string)
+	if strings.TrimPrefix(strings.ToLower(typ), "application/") == jwt.AccessTokenType {
+		return &jwt.ClaimError{Claim: "typ", Err: jwt.ErrTokenInvalidType}
+	}
+
+	return nil
+}
+
+// validateAuthorizedParty requires the `azp` claim to be the client id if it is present or the
+// token was issued to more than one audience.
+func (v *Verifier) validateAuthorizedParty(ctx *jwt.ValidationContext) error {
+	azp, ok := jwt.ClaimValue[string](ctx, "azp")
+	if !ok && len(ctx.Claims.GetAudience()) <= 1 {
+		return nil
+	}
+
+	if !ok || azp != v.ClientID {
+		return &jwt.ClaimError{Claim: "azp", Err: ErrAuthorizedPartyMismatch}
+	}
+
+	return nil
+}
+
+// validateNonce requires the `nonce` claim to be the nonce of the authentication request.
+func validateNonce(nonce string) jwt.Validator {
+	return func(ctx *jwt.ValidationContext) error {
+		value, ok := jwt.ClaimValue[string](ctx, "nonce")
+		if !ok {
+			return &jwt.ClaimError{Claim: "nonce", Err: jwt.ErrClaimIsRequired}
+		}
+
+		if subtle.ConstantTimeCompare([]byte(value), []byte(nonce)) != 1 {
+			return &jwt.ClaimError{Claim: "nonce", Err: ErrNonceMismatch}
+		}
+
+		return nil
+	}
+}
+
+// validateAuthTime requires the `auth_time` claim to be at most maxAge ago.
+func validateAuthTime(maxAge time.Duration, leeway time.Duration) jwt.Validator {
+	return func(ctx *jwt.ValidationContext) error {
+		authTime, ok := jwt.ClaimValue[jwt.NumericDate](ctx, "auth_time")
+		if !ok {
+			return &jwt.ClaimError{Claim: "auth_time", Err: jwt.ErrClaimIsRequired}
+		}
+
+		if ctx.Now.After(authTime.Add(maxAge + leeway)) {
+			return &jwt.ClaimError{Claim: "auth_time", Err: ErrAuthenticationTooOld}
+		}
+
+		return nil
+	}
+}
+
+// validateHash requires the claim, `at_hash` or `c_hash`, to be the token hash of value. If
+// the claim is not required, tokens without the claim are accepted.
+func validateHash(claim string, value string, required bool, mismatch error) jwt.Validator {
+	return func(ctx *jwt.ValidationContext) error {
+		hash, ok := jwt.ClaimValue[string](ctx, claim)
+		if !ok {
+			if required {
+				return &jwt.ClaimError{Claim: claim, Err: jwt.ErrClaimIsRequired}
+			}
+			return nil
+		}
+
+		alg, _ := ctx.Header["alg"].(string)
+		expected, err := TokenHash(alg, value)
+		if err != nil {
+			return &jwt.ClaimError{Claim: claim, Err: err}
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) != 1 {
+			return &jwt.ClaimError{Claim: claim, Err: mismatch}
+		}
+
+		return nil
+	}
+}
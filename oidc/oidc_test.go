@@ -0,0 +1,219 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "client-1"
+)
+
+func newTestProvider(t *testing.T) (*rsa.PrivateKey, *Verifier) {
+	t.Helper()
+
+	key, err := jwt.GenerateRSAKey(2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	jwk, _ := jwt.NewRSAJWK(&key.PublicKey, "key-1", "RS256")
+
+	return key, NewVerifier(testIssuer, testClientID, &jwt.JWKS{Keys: []jwt.JWK{*jwk}})
+}
+
+func validTestClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":            testIssuer,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "user@example.com",
+		"email_verified": true,
+	}
+}
+
+func signTestIDToken(t *testing.T, key *rsa.PrivateKey, header map[string]any, claims map[string]any) string {
+	t.Helper()
+
+	if header == nil {
+		header = map[string]any{"alg": "RS256", "typ": "JWT", "kid": "key-1"}
+	}
+
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signingString := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	sig, err := jwt.SigningMethodRS256.SignRSA(signingString, key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, verifier := newTestProvider(t)
+
+	claims := validTestClaims()
+	claims["nonce"] = "nonce-1"
+	idToken := signTestIDToken(t, key, nil, claims)
+
+	token, err := verifier.Verify(idToken, &VerifyOptions{Nonce: "nonce-1"})
+	if err != nil {
+		t.Fatalf("expected ID token without nbf to verify, got %v", err)
+	}
+
+	if !token.Valid || token.Claims.Subject != "user-1" || token.Claims.Email != "user@example.com" || !token.Claims.EmailVerified {
+		t.Errorf("unexpected token %+v", token.Claims)
+	}
+}
+
+func TestVerifier_Rejects(t *testing.T) {
+	key, verifier := newTestProvider(t)
+
+	tests := map[string]struct {
+		header map[string]any
+		modify func(claims map[string]any)
+		opts   *VerifyOptions
+		err    error
+	}{
+		"wrong issuer":     {modify: func(c map[string]any) { c["iss"] = "https://evil.example.com" }, err: jwt.ErrIssuerMismatch},
+		"wrong audience":   {modify: func(c map[string]any) { c["aud"] = "client-2" }, err: jwt.ErrAudienceMismatch},
+		"missing iat":      {modify: func(c map[string]any) { delete(c, "iat") }, err: jwt.ErrIssuedAtIsRequired},
+		"expired":          {modify: func(c map[string]any) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, err: jwt.ErrTokenExpired},
+		"not valid yet":    {modify: func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() }, err: jwt.ErrTokenNotValidYet},
+		"missing nonce":    {opts: &VerifyOptions{Nonce: "nonce-1"}, err: jwt.ErrClaimIsRequired},
+		"wrong nonce":      {modify: func(c map[string]any) { c["nonce"] = "nonce-2" }, opts: &VerifyOptions{Nonce: "nonce-1"}, err: ErrNonceMismatch},
+		"multi audience":   {modify: func(c map[string]any) { c["aud"] = []string{testClientID, "client-2"} }, err: ErrAuthorizedPartyMismatch},
+		"wrong azp":        {modify: func(c map[string]any) { c["azp"] = "client-2" }, err: ErrAuthorizedPartyMismatch},
+		"missing alg":      {header: map[string]any{"kid": "key-1"}, err: jwt.ErrTokenInvalidAlgorithm},
+		"disallowed alg":   {header: map[string]any{"alg": "none", "kid": "key-1"}, err: jwt.ErrTokenInvalidAlgorithm},
+		"access token typ": {header: map[string]any{"alg": "RS256", "kid": "key-1", "typ": "at+jwt"}, err: jwt.ErrTokenInvalidType},
+		"missing auth_time": {
+			opts: &VerifyOptions{MaxAge: time.Hour},
+			err:  jwt.ErrClaimIsRequired,
+		},
+		"authentication too old": {
+			modify: func(c map[string]any) { c["auth_time"] = time.Now().Add(-2 * time.Hour).Unix() },
+			opts:   &VerifyOptions{MaxAge: time.Hour},
+			err:    ErrAuthenticationTooOld,
+		},
+		"wrong at_hash": {
+			modify: func(c map[string]any) { c["at_hash"] = "wrong" },
+			opts:   &VerifyOptions{AccessToken: "access-token"},
+			err:    ErrAccessTokenHashMismatch,
+		},
+		"missing c_hash": {
+			opts: &VerifyOptions{Code: "code"},
+			err:  jwt.ErrClaimIsRequired,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := validTestClaims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			_, err := verifier.Verify(signTestIDToken(t, key, tt.header, claims), tt.opts)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_IssuerAliases(t *testing.T) {
+	key, verifier := newTestProvider(t)
+	verifier.IssuerAliases = []string{"issuer.example.com"}
+
+	for _, issuer := range []string{testIssuer, "issuer.example.com"} {
+		claims := validTestClaims()
+		claims["iss"] = issuer
+		if _, err := verifier.Verify(signTestIDToken(t, key, nil, claims), nil); err != nil {
+			t.Errorf("expected issuer %q to be accepted, got %v", issuer, err)
+		}
+	}
+
+	claims := validTestClaims()
+	claims["iss"] = "evil.example.com"
+	if _, err := verifier.Verify(signTestIDToken(t, key, nil, claims), nil); !errors.Is(err, jwt.ErrIssuerMismatch) {
+		t.Errorf("expected ErrIssuerMismatch for unknown issuer, got %v", err)
+	}
+}
+
+func TestVerifier_AuthorizedPartyAndHashes(t *testing.T) {
+	key, verifier := newTestProvider(t)
+
+	atHash, _ := TokenHash("RS256", "access-token")
+	cHash, _ := TokenHash("RS256", "code")
+
+	claims := validTestClaims()
+	claims["aud"] = []string{testClientID, "client-2"}
+	claims["azp"] = testClientID
+	claims["auth_time"] = time.Now().Add(-time.Minute).Unix()
+	claims["at_hash"] = atHash
+	claims["c_hash"] = cHash
+
+	opts := &VerifyOptions{MaxAge: time.Hour, AccessToken: "access-token", Code: "code"}
+	token, err := verifier.Verify(signTestIDToken(t, key, nil, claims), opts)
+	if err != nil {
+		t.Fatalf("expected ID token to verify, got %v", err)
+	}
+
+	if token.Claims.AuthorizedParty != testClientID || token.Claims.AuthTime == nil {
+		t.Errorf("unexpected claims %+v", token.Claims)
+	}
+
+	// at_hash is optional in the code flow, where the token endpoint returns both tokens.
+	delete(claims, "at_hash")
+	if _, err := verifier.Verify(signTestIDToken(t, key, nil, claims), opts); err != nil {
+		t.Errorf("expected missing at_hash to be accepted, got %v", err)
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	// https://openid.net/specs/openid-connect-core-1_0.html#code-id_tokenExample
+	hash, err := TokenHash("RS256", "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk")
+	if err != nil || hash != "LDktKdoQak3Pk0cnXxCltA" {
+		t.Errorf("unexpected c_hash %s (%v)", hash, err)
+	}
+
+	if _, err := TokenHash("HS1", "value"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+}
+
+func TestVerifyIDToken_CustomClaims(t *testing.T) {
+	key, verifier := newTestProvider(t)
+
+	type tenantClaims struct {
+		Tenant string `json:"tenant"`
+		IDTokenClaims
+	}
+
+	claims := validTestClaims()
+	claims["tenant"] = "acme"
+
+	token, err := VerifyIDToken[tenantClaims](verifier, signTestIDToken(t, key, nil, claims), &VerifyOptions{
+		Validators: []jwt.Validator{jwt.ClaimOneOf("tenant", "acme")},
+	})
+	if err != nil {
+		t.Fatalf("expected custom claims to verify, got %v", err)
+	}
+
+	if token.Claims.Tenant != "acme" || token.Claims.Email != "user@example.com" {
+		t.Errorf("unexpected claims %+v", token.Claims)
+	}
+}
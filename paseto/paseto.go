@@ -0,0 +1,328 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+var (
+	ErrTokenMalformed      = errors.New("paseto: token is malformed")
+	ErrInvalidHeader       = errors.New("paseto: token has an unexpected version or purpose")
+	ErrFooterMismatch      = errors.New("paseto: token footer does not match the expected footer")
+	ErrInvalidKey          = errors.New("paseto: key is invalid")
+	ErrSignatureInvalid    = errors.New("paseto: signature verification failed")
+	ErrAuthenticationError = errors.New("paseto: message authentication failed")
+)
+
+const (
+	headerPublic = "v4.public."
+	headerLocal  = "v4.local."
+
+	// KeySize is the size of a v4.local symmetric key in bytes.
+	KeySize = 32
+
+	nonceSize = 32
+	macSize   = 32
+)
+
+// Token represents a verified or decrypted PASETO token.
+type Token[T jwt.Claims] struct {
+	Raw    string // Raw contains the raw token
+	Claims *T     // Claims is the decoded payload of the token
+	Footer []byte // Footer is the unencrypted, authenticated footer of the token
+}
+
+// RegisteredClaims are the registered claims of https://github.com/paseto-standard/paseto-spec/blob/master/docs/02-Implementation-Guide/04-Claims.md.
+// In contrast to JWT, PASETO encodes dates as ISO 8601 strings. RegisteredClaims implements
+// the jwt.Claims interface, so tokens can be validated with jwt.ExpectedClaims.
+type RegisteredClaims struct {
+	Issuer    string     `json:"iss,omitempty"`
+	Subject   string     `json:"sub,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	ExpiresAt *time.Time `json:"exp,omitempty"`
+	NotBefore *time.Time `json:"nbf,omitempty"`
+	IssuedAt  *time.Time `json:"iat,omitempty"`
+	ID        string     `json:"jti,omitempty"`
+}
+
+// GetIssuer implements the jwt.Claims interface.
+func (c RegisteredClaims) GetIssuer() string {
+	return c.Issuer
+}
+
+// GetSubject implements the jwt.Claims interface.
+func (c RegisteredClaims) GetSubject() string {
+	return c.Subject
+}
+
+// GetAudience implements the jwt.Claims interface.
+func (c RegisteredClaims) GetAudience() jwt.Audience {
+	if c.Audience == "" {
+		return nil
+	}
+
+	return jwt.Audience{c.Audience}
+}
+
+// GetExpirationTime implements the jwt.Claims interface.
+func (c RegisteredClaims) GetExpirationTime() *jwt.NumericDate {
+	return toNumericDate(c.ExpiresAt)
+}
+
+// GetNotBefore implements the jwt.Claims interface.
+func (c RegisteredClaims) GetNotBefore() *jwt.NumericDate {
+	return toNumericDate(c.NotBefore)
+}
+
+// GetIssuedAt implements the jwt.Claims interface.
+func (c RegisteredClaims) GetIssuedAt() *jwt.NumericDate {
+	return toNumericDate(c.IssuedAt)
+}
+
+// GetID implements the jwt.Claims interface.
+func (c RegisteredClaims) GetID() string {
+	return c.ID
+}
+
+// Sign creates a v4.public token of the claims, signed with the Ed25519 signer. The
+// footer is transmitted in plain text but authenticated. The implicit assertion is
+// authenticated without being part of the token, the verifier has to supply the same value.
+func Sign[T jwt.Claims](claims *T, signer jwt.Signer, footer []byte, implicit []byte) (string, error) {
+	if signer == nil {
+		return "", ErrInvalidKey
+	}
+
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := signer.Sign(pae([]byte(headerPublic), message, footer, implicit))
+	if err != nil {
+		return "", err
+	}
+
+	return buildToken(headerPublic, append(message, sig...), footer), nil
+}
+
+// Verify verifies a v4.public token with the Ed25519 public key, checks that the token
+// carries the expected footer and validates its claims the same way as jwt.VerifyToken.
+func Verify[T jwt.Claims](token string, key *jwt.PublicKey, footer []byte, implicit []byte, expected *jwt.ExpectedClaims) (*Token[T], error) {
+	if key == nil || len(*key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	payload, err := parseToken(token, headerPublic, footer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < ed25519.SignatureSize {
+		return nil, ErrTokenMalformed
+	}
+
+	message, sig := payload[:len(payload)-ed25519.SignatureSize], payload[len(payload)-ed25519.SignatureSize:]
+	if !ed25519.Verify(ed25519.PublicKey(*key), pae([]byte(headerPublic), message, footer, implicit), sig) {
+		return nil, ErrSignatureInvalid
+	}
+
+	return decodeClaims[T](token, message, footer, expected)
+}
+
+// Encrypt creates a v4.local token of the claims, encrypted with XChaCha20 and authenticated
+// with keyed BLAKE2b using the 32 byte symmetric key. The footer is transmitted in plain text
+// but authenticated, the implicit assertion is authenticated without being part of the token.
+func Encrypt[T jwt.Claims](claims *T, key []byte, footer []byte, implicit []byte) (string, error) {
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return encrypt(message, key, footer, implicit, nonce)
+}
+
+// Decrypt decrypts a v4.local token with the 32 byte symmetric key, checks that the token
+// carries the expected footer and validates its claims the same way as jwt.VerifyToken.
+func Decrypt[T jwt.Claims](token string, key []byte, footer []byte, implicit []byte, expected *jwt.ExpectedClaims) (*Token[T], error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	payload, err := parseToken(token, headerLocal, footer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < nonceSize+macSize {
+		return nil, ErrTokenMalformed
+	}
+
+	nonce := payload[:nonceSize]
+	ciphertext := payload[nonceSize : len(payload)-macSize]
+	mac := payload[len(payload)-macSize:]
+
+	encryptionKey, counterNonce, authKey := deriveLocalKeys(key, nonce)
+
+	if subtle.ConstantTimeCompare(mac, authenticate(authKey, []byte(headerLocal), nonce, ciphertext, footer, implicit)) != 1 {
+		return nil, ErrAuthenticationError
+	}
+
+	message := make([]byte, len(ciphertext))
+	if err := xorKeyStream(message, ciphertext, encryptionKey, counterNonce); err != nil {
+		return nil, err
+	}
+
+	return decodeClaims[T](token, message, footer, expected)
+}
+
+// encrypt implements v4.local encryption with the given nonce, see
+// https://github.com/paseto-standard/paseto-spec/blob/master/docs/01-Protocol-Versions/Version4.md#encrypt.
+func encrypt(message []byte, key []byte, footer []byte, implicit []byte, nonce []byte) (string, error) {
+	if len(key) != KeySize {
+		return "", ErrInvalidKey
+	}
+
+	encryptionKey, counterNonce, authKey := deriveLocalKeys(key, nonce)
+
+	ciphertext := make([]byte, len(message))
+	if err := xorKeyStream(ciphertext, message, encryptionKey, counterNonce); err != nil {
+		return "", err
+	}
+
+	mac := authenticate(authKey, []byte(headerLocal), nonce, ciphertext, footer, implicit)
+
+	payload := make([]byte, 0, nonceSize+len(ciphertext)+macSize)
+	payload = append(payload, nonce...)
+	payload = append(payload, ciphertext...)
+	payload = append(payload, mac...)
+
+	return buildToken(headerLocal, payload, footer), nil
+}
+
+// deriveLocalKeys splits the symmetric key into the encryption key, the XChaCha20 nonce and
+// the authentication key for the given random nonce.
+func deriveLocalKeys(key []byte, nonce []byte) ([]byte, []byte, []byte) {
+	tmp, _ := blake2b.New(56, key)
+	tmp.Write([]byte("paseto-encryption-key"))
+	tmp.Write(nonce)
+	derived := tmp.Sum(nil)
+
+	auth, _ := blake2b.New(32, key)
+	auth.Write([]byte("paseto-auth-key-for-aead"))
+	auth.Write(nonce)
+
+	return derived[:32], derived[32:], auth.Sum(nil)
+}
+
+// authenticate computes the keyed BLAKE2b MAC over the pre-authentication encoding of pieces.
+func authenticate(authKey []byte, pieces ...[]byte) []byte {
+	mac, _ := blake2b.New(macSize, authKey)
+	mac.Write(pae(pieces...))
+	return mac.Sum(nil)
+}
+
+// xorKeyStream encrypts or decrypts src into dst with XChaCha20.
+func xorKeyStream(dst []byte, src []byte, key []byte, nonce []byte) error {
+	c, err := chacha20.NewUnauthenticatedCipher(key, nonce)
+	if err != nil {
+		return err
+	}
+
+	c.XORKeyStream(dst, src)
+	return nil
+}
+
+// pae implements the pre-authentication encoding, see
+// https://github.com/paseto-standard/paseto-spec/blob/master/docs/01-Protocol-Versions/Common.md#pae-definition.
+func pae(pieces ...[]byte) []byte {
+	size := 8
+	for _, piece := range pieces {
+		size += 8 + len(piece)
+	}
+
+	out := make([]byte, 0, size)
+	out = binary.LittleEndian.AppendUint64(out, uint64(len(pieces))&^(1<<63))
+	for _, piece := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(piece))&^(1<<63))
+		out = append(out, piece...)
+	}
+
+	return out
+}
+
+// buildToken joins header, payload and the optional footer to a token string.
+func buildToken(header string, payload []byte, footer []byte) string {
+	token := header + base64.RawURLEncoding.EncodeToString(payload)
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+
+	return token
+}
+
+// parseToken checks the header and footer of a token and returns its decoded payload.
+func parseToken(token string, header string, footer []byte) ([]byte, error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, ErrInvalidHeader
+	}
+
+	body, encodedFooter, hasFooter := strings.Cut(token[len(header):], ".")
+	if hasFooter && strings.Contains(encodedFooter, ".") {
+		return nil, ErrTokenMalformed
+	}
+
+	actualFooter, err := base64.RawURLEncoding.DecodeString(encodedFooter)
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if subtle.ConstantTimeCompare(actualFooter, footer) != 1 {
+		return nil, ErrFooterMismatch
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	return payload, nil
+}
+
+// decodeClaims unmarshals the authenticated message into the claims and validates them.
+func decodeClaims[T jwt.Claims](token string, message []byte, footer []byte, expected *jwt.ExpectedClaims) (*Token[T], error) {
+	parsed := &Token[T]{Raw: token, Footer: footer}
+	if err := json.Unmarshal(message, &parsed.Claims); err != nil || parsed.Claims == nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := jwt.ValidateClaims(*parsed.Claims, expected); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// toNumericDate converts an optional time into an optional jwt.NumericDate.
+func toNumericDate(t *time.Time) *jwt.NumericDate {
+	if t == nil {
+		return nil
+	}
+
+	return &jwt.NumericDate{Time: *t}
+}
@@ -0,0 +1,201 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+type vectorClaims struct {
+	Data string `json:"data"`
+	RegisteredClaims
+}
+
+// Test vectors 4-E-1 and 4-S-1 from https://github.com/paseto-standard/test-vectors/blob/master/v4.json
+const (
+	vectorLocalKey   = "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f"
+	vectorLocalToken = "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg"
+
+	vectorSecretKey   = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	vectorPublicToken = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+)
+
+func TestEncrypt_TestVector(t *testing.T) {
+	key, _ := hex.DecodeString(vectorLocalKey)
+	message := []byte(`{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`)
+
+	token, err := encrypt(message, key, nil, nil, make([]byte, nonceSize))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != vectorLocalToken {
+		t.Errorf("unexpected token\nexpected %s\ngot      %s", vectorLocalToken, token)
+	}
+}
+
+func TestSign_TestVector(t *testing.T) {
+	seed, _ := hex.DecodeString(vectorSecretKey)
+	privateKey := jwt.PrivateKey(seed)
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+
+	sig := ed25519.Sign(ed25519.PrivateKey(privateKey), pae([]byte(headerPublic), message, nil, nil))
+	if token := buildToken(headerPublic, append(message, sig...), nil); token != vectorPublicToken {
+		t.Errorf("unexpected token\nexpected %s\ngot      %s", vectorPublicToken, token)
+	}
+}
+
+func newTestClaims() *vectorClaims {
+	now := time.Now()
+	exp := now.Add(time.Hour)
+
+	return &vectorClaims{
+		Data: "hello",
+		RegisteredClaims: RegisteredClaims{
+			Issuer:    "https://issuer.example.com",
+			Subject:   "user-1",
+			Audience:  "api",
+			ExpiresAt: &exp,
+			NotBefore: &now,
+			IssuedAt:  &now,
+		},
+	}
+}
+
+func newTestExpectedClaims() *jwt.ExpectedClaims {
+	return &jwt.ExpectedClaims{
+		Issuer:   "https://issuer.example.com",
+		Subject:  "user-1",
+		Audience: []string{"api"},
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pub, priv := jwt.PublicKey(publicKey), jwt.PrivateKey(privateKey)
+	footer := []byte(`{"kid":"key-1"}`)
+
+	token, err := Sign(newTestClaims(), &priv, footer, []byte("implicit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token, "v4.public.") {
+		t.Fatalf("expected v4.public token, got %s", token)
+	}
+
+	parsed, err := Verify[vectorClaims](token, &pub, footer, []byte("implicit"), newTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected token to verify, got %v", err)
+	}
+	if parsed.Claims.Data != "hello" || string(parsed.Footer) != string(footer) {
+		t.Errorf("unexpected token contents: %+v", parsed)
+	}
+
+	if _, err := Verify[vectorClaims](token, &pub, []byte(`{"kid":"key-2"}`), []byte("implicit"), newTestExpectedClaims()); !errors.Is(err, ErrFooterMismatch) {
+		t.Errorf("expected ErrFooterMismatch, got %v", err)
+	}
+	if _, err := Verify[vectorClaims](token, &pub, footer, []byte("other"), newTestExpectedClaims()); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("expected ErrSignatureInvalid for implicit assertion mismatch, got %v", err)
+	}
+
+	otherPublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub := jwt.PublicKey(otherPublicKey)
+	if _, err := Verify[vectorClaims](token, &otherPub, footer, []byte("implicit"), newTestExpectedClaims()); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("expected ErrSignatureInvalid for wrong key, got %v", err)
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+
+	token, err := Encrypt(newTestClaims(), key, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token, "v4.local.") {
+		t.Fatalf("expected v4.local token, got %s", token)
+	}
+
+	parsed, err := Decrypt[vectorClaims](token, key, nil, nil, newTestExpectedClaims())
+	if err != nil {
+		t.Fatalf("expected token to decrypt, got %v", err)
+	}
+	if parsed.Claims.Data != "hello" {
+		t.Errorf("expected data 'hello', got %q", parsed.Claims.Data)
+	}
+
+	if _, err := Decrypt[vectorClaims](token, key, []byte("footer"), nil, newTestExpectedClaims()); !errors.Is(err, ErrFooterMismatch) {
+		t.Errorf("expected ErrFooterMismatch, got %v", err)
+	}
+	if _, err := Decrypt[vectorClaims](token, key, nil, []byte("implicit"), newTestExpectedClaims()); !errors.Is(err, ErrAuthenticationError) {
+		t.Errorf("expected ErrAuthenticationError for implicit assertion mismatch, got %v", err)
+	}
+
+	otherKey := make([]byte, KeySize)
+	rand.Read(otherKey)
+	if _, err := Decrypt[vectorClaims](token, otherKey, nil, nil, newTestExpectedClaims()); !errors.Is(err, ErrAuthenticationError) {
+		t.Errorf("expected ErrAuthenticationError for wrong key, got %v", err)
+	}
+	if _, err := Decrypt[vectorClaims](token, key[:16], nil, nil, newTestExpectedClaims()); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestDecrypt_Tampered(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+
+	token, _ := Encrypt(newTestClaims(), key, nil, nil)
+	tampered := token[:len(token)-10] + flipChar(token[len(token)-10]) + token[len(token)-9:]
+
+	if _, err := Decrypt[vectorClaims](tampered, key, nil, nil, newTestExpectedClaims()); !errors.Is(err, ErrAuthenticationError) {
+		t.Errorf("expected ErrAuthenticationError, got %v", err)
+	}
+}
+
+func TestVerify_WrongPurpose(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub := jwt.PublicKey(publicKey)
+
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	token, _ := Encrypt(newTestClaims(), key, nil, nil)
+
+	if _, err := Verify[vectorClaims](token, &pub, nil, nil, newTestExpectedClaims()); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestVerify_ValidatesClaims(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pub, priv := jwt.PublicKey(publicKey), jwt.PrivateKey(privateKey)
+
+	claims := newTestClaims()
+	expired := time.Now().Add(-time.Minute)
+	claims.ExpiresAt = &expired
+
+	token, _ := Sign(claims, &priv, nil, nil)
+	if _, err := Verify[vectorClaims](token, &pub, nil, nil, newTestExpectedClaims()); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("expected jwt.ErrTokenExpired, got %v", err)
+	}
+
+	token, _ = Sign(newTestClaims(), &priv, nil, nil)
+	expected := newTestExpectedClaims()
+	expected.Issuer = "https://other.example.com"
+	if _, err := Verify[vectorClaims](token, &pub, nil, nil, expected); !errors.Is(err, jwt.ErrIssuerMismatch) {
+		t.Errorf("expected jwt.ErrIssuerMismatch, got %v", err)
+	}
+}
+
+func flipChar(c byte) string {
+	if c == 'A' {
+		return "B"
+	}
+	return "A"
+}
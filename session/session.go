@@ -0,0 +1,215 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+	strivia_random "github.com/loggdme/strivia/random"
+)
+
+var (
+	ErrRefreshTokenExpired  = errors.New("session: refresh token is expired")
+	ErrRefreshTokenRevoked  = errors.New("session: refresh token is revoked")
+	ErrRefreshTokenReused   = errors.New("session: refresh token was already used, session revoked")
+	ErrManagerNotConfigured = errors.New("session: manager requires Keys and a Store")
+)
+
+// AccessClaims are the claims of the access tokens issued by a Manager. The `sid` claim
+// contains the family of the refresh token the access token was issued with.
+type AccessClaims struct {
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is a short-lived access JWT together with the opaque refresh token used to
+// obtain the next pair.
+type TokenPair struct {
+	SessionID             string
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// The TTLs of [NewManager], which also apply to a Manager without TTLs.
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Manager issues access/refresh token pairs. Refresh tokens are single use: every refresh
+// rotates the refresh token, and presenting an already used refresh token again revokes the
+// whole token family, as recommended in
+// https://datatracker.ietf.org/doc/html/draft-ietf-oauth-security-topics#section-4.14.2.
+//
+// A Manager created as a struct literal uses the TTLs of [NewManager] for zero TTLs. Without
+// Keys or Store, its methods return [ErrManagerNotConfigured].
+type Manager struct {
+	Keys            *jwt.KeySet
+	Store           Store
+	Issuer          string
+	Audience        []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	now func() time.Time
+}
+
+// NewManager creates and returns a new Manager which signs access tokens with the active key
+// of keys and persists refresh tokens in store. Access tokens are valid for 15 minutes and
+// refresh tokens for 30 days unless the TTLs are changed on the returned Manager.
+func NewManager(keys *jwt.KeySet, store Store, issuer string, audience []string) *Manager {
+	return &Manager{
+		Keys:            keys,
+		Store:           store,
+		Issuer:          issuer,
+		Audience:        audience,
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+		now:             time.Now,
+	}
+}
+
+// Issue starts a new session for subject, e.g. after a successful login, and returns its
+// first token pair.
+func (m *Manager) Issue(subject string) (*TokenPair, error) {
+	if m.Keys == nil || m.Store == nil {
+		return nil, ErrManagerNotConfigured
+	}
+
+	return m.issue(subject, strivia_random.SecureRandomBase32String(20))
+}
+
+// Refresh exchanges a refresh token for a new token pair of the same session. The presented
+// refresh token is invalidated. If it was already used before, the token was most likely
+// stolen and the whole session is revoked.
+func (m *Manager) Refresh(refreshToken string) (*TokenPair, error) {
+	if m.Keys == nil || m.Store == nil {
+		return nil, ErrManagerNotConfigured
+	}
+
+	hash := hashRefreshToken(refreshToken)
+
+	stored, err := m.Store.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// MarkUsed and Create check the revocation of the family atomically, a concurrent
+	// RevokeFamily therefore either fails this refresh or also revokes the issued token.
+	firstUse, err := m.Store.MarkUsed(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !firstUse {
+		if err := m.Store.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if m.clock().After(stored.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	return m.issue(stored.Subject, stored.FamilyID)
+}
+
+// Revoke ends the session the refresh token belongs to, e.g. on logout. Access tokens which
+// were already issued stay valid until they expire.
+func (m *Manager) Revoke(refreshToken string) error {
+	if m.Store == nil {
+		return ErrManagerNotConfigured
+	}
+
+	stored, err := m.Store.Get(hashRefreshToken(refreshToken))
+	if err != nil {
+		return err
+	}
+
+	return m.Store.RevokeFamily(stored.FamilyID)
+}
+
+// VerifyAccessToken verifies an access token issued by this Manager.
+func (m *Manager) VerifyAccessToken(accessToken string) (*jwt.Token[AccessClaims], error) {
+	if m.Keys == nil {
+		return nil, ErrManagerNotConfigured
+	}
+
+	return jwt.VerifyTokenWithKeySet[AccessClaims](accessToken, m.Keys, &jwt.ExpectedClaims{
+		Issuer:   m.Issuer,
+		Audience: m.Audience,
+		Now:      m.clock,
+	})
+}
+
+// issue creates a new token pair for subject within the given token family.
+func (m *Manager) issue(subject string, familyID string) (*TokenPair, error) {
+	now := m.clock()
+
+	accessTokenTTL, refreshTokenTTL := m.AccessTokenTTL, m.RefreshTokenTTL
+	if accessTokenTTL == 0 {
+		accessTokenTTL = defaultAccessTokenTTL
+	}
+	if refreshTokenTTL == 0 {
+		refreshTokenTTL = defaultRefreshTokenTTL
+	}
+
+	pair := &TokenPair{
+		SessionID:             familyID,
+		AccessTokenExpiresAt:  now.Add(accessTokenTTL),
+		RefreshToken:          strivia_random.SecureRandomBase32String(32),
+		RefreshTokenExpiresAt: now.Add(refreshTokenTTL),
+	}
+
+	accessToken, err := jwt.NewToken(&AccessClaims{
+		SessionID: familyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.Issuer,
+			Subject:   subject,
+			Audience:  m.Audience,
+			ExpiresAt: &jwt.NumericDate{Time: pair.AccessTokenExpiresAt},
+			NotBefore: &jwt.NumericDate{Time: now},
+			IssuedAt:  &jwt.NumericDate{Time: now},
+			ID:        strivia_random.SecureRandomBase32String(20),
+		},
+	}).SignedStringWithKeySet(m.Keys)
+	if err != nil {
+		return nil, err
+	}
+	pair.AccessToken = accessToken
+
+	err = m.Store.Create(&RefreshToken{
+		Hash:      hashRefreshToken(pair.RefreshToken),
+		FamilyID:  familyID,
+		Subject:   subject,
+		IssuedAt:  now,
+		ExpiresAt: pair.RefreshTokenExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// clock returns the current time, also for managers which were not created with
+// [NewManager].
+func (m *Manager) clock() time.Time {
+	if m.now == nil {
+		return time.Now()
+	}
+
+	return m.now()
+}
+
+// hashRefreshToken returns the hex encoded SHA-256 hash of a refresh token, which is used as
+// its key in the Store.
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,262 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loggdme/strivia/jwt"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	key := jwt.PrivateKey(privateKey)
+
+	keys, err := jwt.NewKeySet(jwt.SigningKey{Kid: "key-1", PrivateKey: &key})
+	if err != nil {
+		t.Fatalf("unexpected error creating key set: %v", err)
+	}
+
+	return NewManager(keys, NewMemoryStore(), "loggd.me", []string{"loggd.me"})
+}
+
+func TestManager_IssueAndVerify(t *testing.T) {
+	m := newTestManager(t)
+
+	pair, err := m.Issue("user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" || pair.SessionID == "" {
+		t.Fatalf("expected a complete token pair, got %+v", pair)
+	}
+
+	token, err := m.VerifyAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("expected access token to verify, got %v", err)
+	}
+	if token.Claims.Subject != "user-1" {
+		t.Errorf("expected subject 'user-1', got %q", token.Claims.Subject)
+	}
+	if token.Claims.SessionID != pair.SessionID {
+		t.Errorf("expected sid %q, got %q", pair.SessionID, token.Claims.SessionID)
+	}
+}
+
+func TestManager_RefreshRotates(t *testing.T) {
+	m := newTestManager(t)
+
+	first, _ := m.Issue("user-1")
+	second, err := m.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("expected refresh token to be rotated")
+	}
+	if second.SessionID != first.SessionID {
+		t.Error("expected rotated pair to belong to the same session")
+	}
+
+	if _, err := m.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("expected rotated refresh token to be usable, got %v", err)
+	}
+}
+
+func TestManager_ReuseRevokesFamily(t *testing.T) {
+	m := newTestManager(t)
+
+	first, _ := m.Issue("user-1")
+	second, _ := m.Refresh(first.RefreshToken)
+
+	if _, err := m.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	if _, err := m.Refresh(second.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected the whole family to be revoked, got %v", err)
+	}
+
+	other, _ := m.Issue("user-1")
+	if _, err := m.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("expected other sessions to be unaffected, got %v", err)
+	}
+}
+
+func TestManager_ConcurrentRefreshOnlyOneWins(t *testing.T) {
+	m := newTestManager(t)
+	pair, _ := m.Issue("user-1")
+
+	var succeeded atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := m.Refresh(pair.RefreshToken); err == nil {
+				succeeded.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if succeeded.Load() != 1 {
+		t.Errorf("expected exactly one refresh to succeed, got %d", succeeded.Load())
+	}
+}
+
+func TestManager_ExpiredAndUnknownRefreshToken(t *testing.T) {
+	m := newTestManager(t)
+	pair, _ := m.Issue("user-1")
+
+	m.now = func() time.Time { return time.Now().Add(m.RefreshTokenTTL + time.Minute) }
+	if _, err := m.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Errorf("expected ErrRefreshTokenExpired, got %v", err)
+	}
+
+	if _, err := m.Refresh("unknown"); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Errorf("expected ErrRefreshTokenNotFound, got %v", err)
+	}
+}
+
+func TestManager_Revoke(t *testing.T) {
+	m := newTestManager(t)
+	pair, _ := m.Issue("user-1")
+
+	if err := m.Revoke(pair.RefreshToken); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := m.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected ErrRefreshTokenRevoked, got %v", err)
+	}
+}
+
+func TestManager_RefreshRacingReuseDoesNotSurviveRevocation(t *testing.T) {
+	m := newTestManager(t)
+
+	for range 50 {
+		first, _ := m.Issue("user-1")
+		second, _ := m.Refresh(first.RefreshToken)
+
+		var rotated *TokenPair
+		var wg sync.WaitGroup
+		wg.Go(func() { rotated, _ = m.Refresh(second.RefreshToken) })
+		wg.Go(func() { m.Refresh(first.RefreshToken) })
+		wg.Wait()
+
+		if rotated == nil {
+			continue
+		}
+		if _, err := m.Refresh(rotated.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+			t.Fatalf("expected token issued during the revocation to be revoked, got %v", err)
+		}
+	}
+}
+
+// revokingStore revokes the family of a token right after it was marked as used, which is
+// the interleaving of a refresh racing with the reuse of an older token of the same family.
+type revokingStore struct {
+	*MemoryStore
+}
+
+func (s revokingStore) MarkUsed(hash string) (bool, error) {
+	firstUse, err := s.MemoryStore.MarkUsed(hash)
+	if token, _ := s.Get(hash); token != nil {
+		s.RevokeFamily(token.FamilyID)
+	}
+	return firstUse, err
+}
+
+func TestManager_RefreshFailsIfFamilyIsRevokedConcurrently(t *testing.T) {
+	m := newTestManager(t)
+	pair, _ := m.Issue("user-1")
+
+	m.Store = revokingStore{m.Store.(*MemoryStore)}
+	if _, err := m.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected ErrRefreshTokenRevoked, got %v", err)
+	}
+}
+
+func TestMemoryStore_RevokedFamilyRejectsNewTokens(t *testing.T) {
+	store := NewMemoryStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	store.Create(&RefreshToken{Hash: "a", FamilyID: "family", ExpiresAt: expiresAt})
+	if err := store.RevokeFamily("family"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := store.MarkUsed("a"); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected ErrRefreshTokenRevoked for revoked token, got %v", err)
+	}
+	if err := store.Create(&RefreshToken{Hash: "b", FamilyID: "family", ExpiresAt: expiresAt}); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("expected ErrRefreshTokenRevoked for revoked family, got %v", err)
+	}
+	if err := store.Create(&RefreshToken{Hash: "c", FamilyID: "other", ExpiresAt: expiresAt}); err != nil {
+		t.Errorf("expected other families to be unaffected, got %v", err)
+	}
+}
+
+func TestMemoryStore_PrunesExpiredTokens(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	store.Create(&RefreshToken{Hash: "old", ExpiresAt: now.Add(time.Second)})
+	store.RevokeFamily("revoked")
+
+	now = now.Add(store.PruneInterval / 2)
+	store.Create(&RefreshToken{Hash: "new", ExpiresAt: now.Add(2 * store.PruneInterval)})
+	if _, err := store.Get("old"); err != nil {
+		t.Errorf("expected expired token to be kept until the next pruning, got %v", err)
+	}
+
+	now = now.Add(store.PruneInterval)
+	if err := store.Create(&RefreshToken{Hash: "newer", FamilyID: "revoked", ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Errorf("expected expired revocation to be pruned, got %v", err)
+	}
+
+	if _, err := store.Get("old"); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Errorf("expected expired token to be pruned, got %v", err)
+	}
+	if _, err := store.Get("new"); err != nil {
+		t.Errorf("expected valid token to be kept, got %v", err)
+	}
+	if err := store.Create(&RefreshToken{Hash: "new", ExpiresAt: now.Add(time.Minute)}); !errors.Is(err, ErrRefreshTokenExists) {
+		t.Errorf("expected ErrRefreshTokenExists, got %v", err)
+	}
+}
+
+func TestManager_StructLiteral(t *testing.T) {
+	keys := newTestManager(t).Keys
+	m := &Manager{Keys: keys, Store: &MemoryStore{}, Issuer: "loggd.me", Audience: []string{"loggd.me"}}
+
+	first, err := m.Issue("user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Until(first.AccessTokenExpiresAt) <= 0 || time.Until(first.RefreshTokenExpiresAt) <= 0 {
+		t.Errorf("expected default TTLs, got %+v", first)
+	}
+
+	second, err := m.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("unexpected error refreshing: %v", err)
+	}
+	if _, err := m.VerifyAccessToken(second.AccessToken); err != nil {
+		t.Errorf("expected access token to verify, got %v", err)
+	}
+	if err := m.Revoke(second.RefreshToken); err != nil {
+		t.Errorf("unexpected error revoking: %v", err)
+	}
+
+	if _, err := (&Manager{}).Issue("user-1"); !errors.Is(err, ErrManagerNotConfigured) {
+		t.Errorf("expected ErrManagerNotConfigured, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("session: refresh token not found")
+	ErrRefreshTokenExists   = errors.New("session: refresh token already exists")
+)
+
+// RefreshToken is the server side record of an opaque refresh token. Only the SHA-256 hash
+// of the token is stored, so a leaked store does not leak usable tokens. All refresh tokens
+// descending from the same login share a FamilyID.
+type RefreshToken struct {
+	Hash      string
+	FamilyID  string
+	Subject   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+// Store persists refresh tokens. Implementations must be safe for concurrent use.
+// The revocation of a family must be persisted independently of its tokens and checked
+// atomically by Create and MarkUsed, so a refresh racing with RevokeFamily can not hand out a
+// token which survives the revocation.
+type Store interface {
+	// Create persists a new refresh token. It returns ErrRefreshTokenRevoked if the family of
+	// the token was revoked.
+	Create(token *RefreshToken) error
+	// Get returns the refresh token with the given hash or ErrRefreshTokenNotFound.
+	Get(hash string) (*RefreshToken, error)
+	// MarkUsed atomically marks the refresh token with the given hash as used. It returns
+	// false if the token was already used before, which indicates a replay, and
+	// ErrRefreshTokenRevoked if the token or its family was revoked.
+	MarkUsed(hash string) (bool, error)
+	// RevokeFamily revokes all refresh tokens of the given family, including tokens which are
+	// created for the family afterwards.
+	RevokeFamily(familyID string) error
+}
+
+// defaultPruneInterval is the PruneInterval of [NewMemoryStore] and of a MemoryStore without
+// one.
+const defaultPruneInterval = time.Minute
+
+// MemoryStore is an in-memory Store. Expired tokens and revoked families are pruned
+// periodically, at most once per PruneInterval, when a new token is created. The zero value
+// is an empty store which prunes every minute.
+type MemoryStore struct {
+	PruneInterval time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]*RefreshToken
+	revoked  map[string]time.Time
+	prunedAt time.Time
+	now      func() time.Time
+}
+
+// NewMemoryStore creates a new, empty MemoryStore which prunes expired tokens every minute.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		PruneInterval: defaultPruneInterval,
+		tokens:        make(map[string]*RefreshToken),
+		revoked:       make(map[string]time.Time),
+		now:           time.Now,
+	}
+}
+
+// Create implements the Store interface.
+func (s *MemoryStore) Create(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tokens[token.Hash]; ok {
+		return ErrRefreshTokenExists
+	}
+
+	s.prune()
+
+	if s.tokens == nil {
+		s.tokens = make(map[string]*RefreshToken)
+	}
+
+	if _, ok := s.revoked[token.FamilyID]; ok {
+		return ErrRefreshTokenRevoked
+	}
+
+	stored := *token
+	s.tokens[token.Hash] = &stored
+
+	return nil
+}
+
+// Get implements the Store interface.
+func (s *MemoryStore) Get(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	t := *token
+	return &t, nil
+}
+
+// MarkUsed implements the Store interface.
+func (s *MemoryStore) MarkUsed(hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return false, ErrRefreshTokenNotFound
+	}
+
+	if _, ok := s.revoked[token.FamilyID]; ok || token.Revoked {
+		return false, ErrRefreshTokenRevoked
+	}
+
+	if token.Used {
+		return false, nil
+	}
+
+	token.Used = true
+
+	return true, nil
+}
+
+// RevokeFamily implements the Store interface. The revocation is kept until the last token
+// of the family expires.
+func (s *MemoryStore) RevokeFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.clock()
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			if token.ExpiresAt.After(expiresAt) {
+				expiresAt = token.ExpiresAt
+			}
+		}
+	}
+
+	if s.revoked == nil {
+		s.revoked = make(map[string]time.Time)
+	}
+	if current, ok := s.revoked[familyID]; !ok || expiresAt.After(current) {
+		s.revoked[familyID] = expiresAt
+	}
+
+	return nil
+}
+
+// prune removes expired tokens and revocations if the last pruning is at least PruneInterval
+// ago. The caller must hold s.mu.
+func (s *MemoryStore) prune() {
+	interval := s.PruneInterval
+	if interval == 0 {
+		interval = defaultPruneInterval
+	}
+
+	now := s.clock()
+	if now.Sub(s.prunedAt) < interval {
+		return
+	}
+	s.prunedAt = now
+
+	for hash, t := range s.tokens {
+		if now.After(t.ExpiresAt) {
+			delete(s.tokens, hash)
+		}
+	}
+
+	for familyID, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, familyID)
+		}
+	}
+}
+
+// clock returns the current time, also for stores which were not created with
+// [NewMemoryStore].
+func (s *MemoryStore) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+
+	return s.now()
+}